@@ -4,18 +4,30 @@ import (
 	"Backend-CharacterVerse/model"
 	"Backend-CharacterVerse/service"
 	"Backend-CharacterVerse/utils/response"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type AddRoleRequest struct {
-	Name        string `json:"name" binding:"required,min=2,max=100"`
-	Description string `json:"description" binding:"required,min=10"`
-	Gender      string `json:"gender" binding:"required,oneof=男 女 其他 未知"`
-	Age         int    `json:"age" binding:"required,min=0,max=120"`
-	VoiceType   string `json:"voice_type" binding:"required"`
-	Tag         string `json:"tag" binding:"required"` // 新增标签字段
+	Name        string   `json:"name" binding:"required,min=2,max=100"`
+	Description string   `json:"description" binding:"required,min=10"`
+	Gender      string   `json:"gender" binding:"required,oneof=男 女 其他 未知"`
+	Age         int      `json:"age" binding:"required,min=0,max=120"`
+	VoiceType   string   `json:"voice_type" binding:"required"`
+	Tag         string   `json:"tag" binding:"required"` // 新增标签字段
+	Tags        []string `json:"tags"`                   // 自定义标签，可选
+
+	VoiceSpeed   float64 `json:"voice_speed"`   // 语速倍率，可选
+	VoicePitch   float64 `json:"voice_pitch"`   // 音调倍率，可选
+	VoiceVolume  float64 `json:"voice_volume"`  // 音量倍率，可选
+	VoiceEmotion string  `json:"voice_emotion"` // 语音情感，可选
+
+	Visibility string `json:"visibility" binding:"omitempty,oneof=private unlisted public"` // 可见性，默认公开
 }
 
 func AddRole(c *gin.Context) {
@@ -40,6 +52,14 @@ func AddRole(c *gin.Context) {
 		req.Age,
 		req.VoiceType,
 		req.Tag, // 新增标签参数
+		req.Tags,
+		model.VoiceParams{
+			Speed:   req.VoiceSpeed,
+			Pitch:   req.VoicePitch,
+			Volume:  req.VoiceVolume,
+			Emotion: req.VoiceEmotion,
+		},
+		req.Visibility,
 	)
 	if err != nil {
 		resp := response.InternalError(err.Error())
@@ -68,6 +88,11 @@ func parsePagination(c *gin.Context) (model.Pagination, *response.Response) {
 		}
 	}
 
+	pagination.Sort = c.Query("sort")
+	if !model.IsValidRoleSort(pagination.Sort) {
+		return pagination, response.BadRequest(fmt.Sprintf("无效的排序方式，有效值为: %v", model.ValidRoleSorts))
+	}
+
 	// 设置默认值
 	if pagination.Page <= 0 {
 		pagination.Page = 1
@@ -84,6 +109,23 @@ func parsePagination(c *gin.Context) (model.Pagination, *response.Response) {
 	return pagination, nil
 }
 
+// 解析标签过滤参数：tag 或逗号分隔的 tags，tag_mode 为 or(默认) 或 and
+func parseRoleFilter(c *gin.Context) (model.RoleFilter, *response.Response) {
+	var rawTags []string
+	if tag := c.Query("tag"); tag != "" {
+		rawTags = append(rawTags, tag)
+	}
+	if tags := c.Query("tags"); tags != "" {
+		rawTags = append(rawTags, strings.Split(tags, ",")...)
+	}
+
+	filter, err := service.ParseRoleFilter(rawTags, c.Query("tag_mode"))
+	if err != nil {
+		return filter, response.BadRequest(err.Error())
+	}
+	return filter, nil
+}
+
 func ListRoles(c *gin.Context) {
 	pagination, resp := parsePagination(c)
 	if resp != nil {
@@ -91,7 +133,16 @@ func ListRoles(c *gin.Context) {
 		return
 	}
 
-	result, err := service.GetRoles(pagination)
+	userID, _ := c.Get("userID")
+	viewerID, _ := userID.(uint)
+
+	filter, resp := parseRoleFilter(c)
+	if resp != nil {
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	result, err := service.GetRoles(viewerID, filter, pagination)
 	if err != nil {
 		resp := response.InternalError(err.Error())
 		c.JSON(resp.Code, resp)
@@ -117,7 +168,10 @@ func GetRolesByUsername(c *gin.Context) {
 		return
 	}
 
-	result, err := service.GetRolesByUsername(username, pagination)
+	userID, _ := c.Get("userID")
+	viewerID, _ := userID.(uint)
+
+	result, err := service.GetRolesByUsername(username, viewerID, pagination)
 	if err != nil {
 		resp := response.InternalError(err.Error())
 		c.JSON(resp.Code, resp)
@@ -136,14 +190,18 @@ func GetRolesByTag(c *gin.Context) {
 	}
 
 	// 从查询参数获取标签
-	tag := c.Query("tag")
-	if tag == "" {
+	filter, resp := parseRoleFilter(c)
+	if resp != nil {
+		c.JSON(resp.Code, resp)
+		return
+	}
+	if len(filter.Tags) == 0 {
 		resp := response.BadRequest("必须提供标签")
 		c.JSON(resp.Code, resp)
 		return
 	}
 
-	result, err := service.GetRolesByTag(tag, pagination)
+	result, err := service.GetRolesByTag(filter, pagination)
 	if err != nil {
 		resp := response.InternalError(err.Error())
 		c.JSON(resp.Code, resp)
@@ -246,3 +304,326 @@ func UpdateRole(c *gin.Context) {
 	resp := response.SuccessWithMessage("角色更新成功", nil)
 	c.JSON(resp.Code, resp)
 }
+
+// 获取角色详情
+func GetRoleDetail(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("role_id"), 10, 32)
+	if err != nil {
+		resp := response.BadRequest("无效的角色ID")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	// 未登录时只能查看非私有角色
+	userID, _ := c.Get("userID")
+	viewerID, _ := userID.(uint)
+
+	detail, err := service.GetRoleDetail(uint(roleID), viewerID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			resp := response.NotFound(err.Error())
+			c.JSON(resp.Code, resp)
+			return
+		}
+		resp := response.InternalError("获取角色详情失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(detail).Code, response.Success(detail))
+}
+
+// 解析角色ID和当前用户ID
+func parseRoleAction(c *gin.Context) (uint, uint, bool) {
+	currentUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(response.Unauthorized("用户未认证").Code, response.Unauthorized("用户未认证"))
+		return 0, 0, false
+	}
+
+	roleID, err := strconv.ParseUint(c.Param("role_id"), 10, 32)
+	if err != nil {
+		resp := response.BadRequest("无效的角色ID")
+		c.JSON(resp.Code, resp)
+		return 0, 0, false
+	}
+
+	return uint(roleID), currentUserID.(uint), true
+}
+
+// 点赞/取消点赞、收藏/取消收藏的公共处理
+func handleRoleInteraction(c *gin.Context, action func(roleID, userID uint) (int64, error), countField, message string) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	count, err := action(roleID, userID)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage(message, gin.H{countField: count})
+	c.JSON(resp.Code, resp)
+}
+
+// 点赞角色
+func LikeRole(c *gin.Context) {
+	handleRoleInteraction(c, service.LikeRole, "like_count", "点赞成功")
+}
+
+// 取消点赞
+func UnlikeRole(c *gin.Context) {
+	handleRoleInteraction(c, service.UnlikeRole, "like_count", "已取消点赞")
+}
+
+// 收藏角色
+func FavoriteRole(c *gin.Context) {
+	handleRoleInteraction(c, service.FavoriteRole, "favorite_count", "收藏成功")
+}
+
+// 取消收藏
+func UnfavoriteRole(c *gin.Context) {
+	handleRoleInteraction(c, service.UnfavoriteRole, "favorite_count", "已取消收藏")
+}
+
+// 获取我收藏的角色
+func GetFavoriteRoles(c *gin.Context) {
+	currentUserID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(response.Unauthorized("用户未认证").Code, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	pagination, resp := parsePagination(c)
+	if resp != nil {
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	result, err := service.GetFavoriteRoles(currentUserID.(uint), pagination)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(result).Code, response.Success(result))
+}
+
+// 标签自动补全
+func GetTags(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	tags, err := service.SearchTags(c.Query("q"), c.Query("official") == "true", limit)
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(tags).Code, response.Success(tags))
+}
+
+// 派生角色
+func ForkRole(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可选
+	var req service.ForkRoleRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := response.BadRequest("参数错误: " + err.Error())
+			c.JSON(resp.Code, resp)
+			return
+		}
+	}
+
+	newRoleID, err := service.ForkRole(roleID, userID, req)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("角色派生成功", gin.H{"role_id": newRoleID})
+	c.JSON(resp.Code, resp)
+}
+
+// 解析版本号参数
+func parseRevisionParam(c *gin.Context) (int, bool) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision <= 0 {
+		resp := response.BadRequest("无效的版本号")
+		c.JSON(resp.Code, resp)
+		return 0, false
+	}
+	return revision, true
+}
+
+// 获取角色版本列表
+func ListRoleRevisions(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	revisions, err := service.ListRoleRevisions(roleID, userID)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(revisions).Code, response.Success(revisions))
+}
+
+// 获取指定版本内容
+func GetRoleRevision(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+	revision, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	result, err := service.GetRoleRevision(roleID, userID, revision)
+	if err != nil {
+		resp := response.NotFound(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(result).Code, response.Success(result))
+}
+
+// 对比两个版本，to 省略时与当前版本对比
+func DiffRoleRevisions(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		resp := response.BadRequest("必须提供有效的起始版本号")
+		c.JSON(resp.Code, resp)
+		return
+	}
+	to := 0
+	if toStr := c.Query("to"); toStr != "" {
+		if to, err = strconv.Atoi(toStr); err != nil || to <= 0 {
+			resp := response.BadRequest("无效的目标版本号")
+			c.JSON(resp.Code, resp)
+			return
+		}
+	}
+
+	diffs, err := service.DiffRoleRevisions(roleID, userID, from, to)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(diffs).Code, response.Success(diffs))
+}
+
+// 恢复到指定版本
+func RestoreRoleRevision(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+	revision, ok := parseRevisionParam(c)
+	if !ok {
+		return
+	}
+
+	newRevision, err := service.RestoreRoleRevision(roleID, userID, revision)
+	if err != nil {
+		resp := response.InternalError(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("角色已恢复", gin.H{"revision": newRevision})
+	c.JSON(resp.Code, resp)
+}
+
+// 获取头像生成状态
+func GetAvatarStatus(c *gin.Context) {
+	roleID, err := strconv.ParseUint(c.Param("role_id"), 10, 32)
+	if err != nil {
+		resp := response.BadRequest("无效的角色ID")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	viewerID, _ := userID.(uint)
+
+	status, err := service.GetAvatarStatus(uint(roleID), viewerID)
+	if err != nil {
+		resp := response.NotFound(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	c.JSON(response.Success(status).Code, response.Success(status))
+}
+
+// 重新生成头像请求参数
+type regenerateAvatarRequest struct {
+	Style string `json:"style"` // 风格预设，为空时不指定风格
+	Count int    `json:"count"` // 候选图片数量，大于1时需挑选后生效
+}
+
+// 重新生成头像
+func RegenerateAvatar(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可选
+	req := regenerateAvatarRequest{Count: 1}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			resp := response.BadRequest("参数错误: " + err.Error())
+			c.JSON(resp.Code, resp)
+			return
+		}
+		if req.Count == 0 {
+			req.Count = 1
+		}
+	}
+
+	job, err := service.RegenerateAvatar(roleID, userID, req.Style, req.Count)
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("头像生成任务已创建", gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"style":      job.Style,
+		"candidates": job.Candidates,
+	})
+	c.JSON(resp.Code, resp)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"Backend-CharacterVerse/service"
+	"Backend-CharacterVerse/utils/response"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传自定义角色头像
+func UploadRoleAvatar(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		resp := response.BadRequest("头像上传失败: " + err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+	if file.Size > service.MaxAvatarUploadSize {
+		resp := response.BadRequest("头像图片不能超过5MB")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		resp := response.BadRequest("读取头像图片失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+	defer f.Close()
+
+	// 多读一个字节以识别超限的请求体
+	data, err := io.ReadAll(io.LimitReader(f, service.MaxAvatarUploadSize+1))
+	if err != nil {
+		resp := response.BadRequest("读取头像图片失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	avatarURL, thumbnails, err := service.UploadRoleAvatar(roleID, userID, data)
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("头像上传成功", gin.H{
+		"avatar_url":        avatarURL,
+		"avatar_thumbnails": thumbnails,
+	})
+	c.JSON(resp.Code, resp)
+}
+
+// 获取头像风格预设
+func GetAvatarStyles(c *gin.Context) {
+	resp := response.Success(service.GetAvatarStyles())
+	c.JSON(resp.Code, resp)
+}
+
+// 获取头像候选图
+func GetAvatarCandidates(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	candidates, err := service.GetAvatarCandidates(roleID, userID)
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.Success(candidates)
+	c.JSON(resp.Code, resp)
+}
+
+// 选择候选图作为头像
+func SelectAvatarCandidate(c *gin.Context) {
+	roleID, userID, ok := parseRoleAction(c)
+	if !ok {
+		return
+	}
+
+	candidateID, err := strconv.ParseUint(c.Param("candidate_id"), 10, 32)
+	if err != nil {
+		resp := response.BadRequest("无效的候选图ID")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	avatarURL, thumbnails, err := service.SelectAvatarCandidate(roleID, userID, uint(candidateID))
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("头像设置成功", gin.H{
+		"avatar_url":        avatarURL,
+		"avatar_thumbnails": thumbnails,
+	})
+	c.JSON(resp.Code, resp)
+}
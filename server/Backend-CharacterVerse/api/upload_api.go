@@ -0,0 +1,96 @@
+package api
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/service"
+	"Backend-CharacterVerse/storage"
+	"Backend-CharacterVerse/utils"
+	"Backend-CharacterVerse/utils/response"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 签发上传服务令牌，客户端凭令牌直接向上传服务上传语音等文件
+func GetUploadToken(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(response.Unauthorized("用户未认证").Code, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	cfg := config.LoadConfig()
+	if cfg.BlobStore != storage.BackendUploadService {
+		resp := response.BadRequest("当前存储方式不支持直接上传")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	token, expiresAt, err := utils.GenerateUploadToken(userID.(uint))
+	if err != nil {
+		resp := response.InternalError("签发上传令牌失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.Success(gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"upload_url": strings.TrimRight(cfg.UploadServiceURL, "/") + "/api/upload",
+	})
+	c.JSON(resp.Code, resp)
+}
+
+// 管理员：试运行存储回收，列出将被删除的无引用文件
+func GetOrphanedObjects(c *gin.Context) {
+	report, err := service.SweepStoredObjects(true)
+	if err != nil {
+		resp := response.InternalError("生成回收报告失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.Success(report)
+	c.JSON(resp.Code, resp)
+}
+
+// 本地存储的文件访问：校验链接签名后返回文件
+func ServeLocalFile(c *gin.Context) {
+	store, ok := storage.Default().(*storage.LocalStore)
+	if !ok {
+		resp := response.NotFound("文件不存在")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := utils.VerifyFileQuery(key, c.Query("expires"), c.Query("signature")); err != nil {
+		resp := response.Forbidden(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	path, err := store.FilePath(key)
+	if err != nil {
+		resp := response.BadRequest("无效的文件名")
+		c.JSON(resp.Code, resp)
+		return
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && info.IsDir()) {
+		resp := response.NotFound("文件不存在")
+		c.JSON(resp.Code, resp)
+		return
+	}
+	if err != nil {
+		resp := response.InternalError("读取文件失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	// 链接在有效期内内容不变，允许客户端缓存
+	c.Header("Cache-Control", "private, max-age=300")
+	c.File(path)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"Backend-CharacterVerse/service"
 	"Backend-CharacterVerse/utils/response"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -18,6 +19,10 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 func Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -49,8 +54,64 @@ func Login(c *gin.Context) {
 
 	// 返回包含用户信息的响应
 	c.JSON(http.StatusOK, response.Success(gin.H{
-		"token":    loginRes.Token,
-		"user_id":  loginRes.UserID,
-		"username": loginRes.Username,
+		"token":              loginRes.Token,
+		"expires_at":         loginRes.ExpiresAt,
+		"refresh_token":      loginRes.RefreshToken,
+		"refresh_expires_at": loginRes.RefreshExpiresAt,
+		"user_id":            loginRes.UserID,
+		"username":           loginRes.Username,
 	}))
 }
+
+// 使用刷新令牌换取新的访问令牌和刷新令牌
+func RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, response.BadRequest("参数错误"))
+		return
+	}
+
+	tokens, err := service.RefreshSession(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, response.Unauthorized(err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.InternalError("刷新令牌失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success(tokens))
+}
+
+// 注销当前设备的登录
+func Logout(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	if err := service.Logout(userID.(uint), c.GetString("sessionID")); err != nil {
+		c.JSON(http.StatusInternalServerError, response.InternalError("注销失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success("已注销"))
+}
+
+// 注销所有设备的登录
+func LogoutAll(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	if err := service.LogoutAllSessions(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, response.InternalError("注销失败"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Success("已注销所有设备"))
+}
@@ -1,15 +1,25 @@
 package api
 
 import (
+	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/service"
 	"Backend-CharacterVerse/utils/response"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetAllVoiceTypes(c *gin.Context) {
-	// 获取所有声音类型信息
-	voiceTypes := model.GetVoiceList()
+	// 获取已启用的声音类型，支持按分类和语言过滤
+	voiceTypes, err := service.ListVoices(database.VoiceFilter{
+		Category: c.Query("category"),
+		Language: c.Query("language"),
+	})
+	if err != nil {
+		resp := response.InternalError("获取声音类型失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
 
 	// 转换为前端需要的格式
 	result := make([]gin.H, 0, len(voiceTypes))
@@ -19,6 +29,9 @@ func GetAllVoiceTypes(c *gin.Context) {
 			"voice_name": info.VoiceName,
 			"category":   info.Category,
 			"sample_url": info.URL,
+			"provider":   info.Provider,
+			"language":   info.Language,
+			"gender":     info.Gender,
 		})
 	}
 
@@ -26,3 +39,78 @@ func GetAllVoiceTypes(c *gin.Context) {
 	resp := response.Success(result)
 	c.JSON(resp.Code, resp)
 }
+
+// 管理员：获取全部声音（包括已禁用）
+func ListAllVoices(c *gin.Context) {
+	voices, err := service.ListVoices(database.VoiceFilter{
+		Category:        c.Query("category"),
+		Language:        c.Query("language"),
+		IncludeDisabled: true,
+		AllOwners:       true,
+	})
+	if err != nil {
+		resp := response.InternalError("获取声音类型失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.Success(voices)
+	c.JSON(resp.Code, resp)
+}
+
+type AddVoiceRequest struct {
+	VoiceType string `json:"voice_type" binding:"required,max=100"`
+	VoiceName string `json:"voice_name" binding:"required,max=100"`
+	SampleURL string `json:"sample_url"`
+	Category  string `json:"category"`
+	Provider  string `json:"provider"`
+	Language  string `json:"language"`
+	Gender    string `json:"gender"`
+}
+
+// 管理员：添加声音
+func AddVoice(c *gin.Context) {
+	var req AddVoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp := response.BadRequest("参数错误: " + err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	voice, err := service.AddVoice(model.Voice{
+		VoiceType: req.VoiceType,
+		VoiceName: req.VoiceName,
+		URL:       model.BlobRef(req.SampleURL),
+		Category:  req.Category,
+		Provider:  req.Provider,
+		Language:  req.Language,
+		Gender:    req.Gender,
+	})
+	if err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("声音添加成功", voice)
+	c.JSON(resp.Code, resp)
+}
+
+// 管理员：更新声音（传入 enabled=false 可禁用）
+func UpdateVoice(c *gin.Context) {
+	var updateData map[string]interface{}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		resp := response.BadRequest("参数错误: " + err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	if err := service.UpdateVoice(c.Param("voice_type"), updateData); err != nil {
+		resp := response.BadRequest(err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("声音更新成功", nil)
+	c.JSON(resp.Code, resp)
+}
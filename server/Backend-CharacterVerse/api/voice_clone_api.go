@@ -0,0 +1,110 @@
+package api
+
+import (
+	"Backend-CharacterVerse/service"
+	"Backend-CharacterVerse/utils/response"
+	"errors"
+	"io"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 参考音频大小上限
+const maxCloneSampleSize = 10 << 20
+
+// 允许的参考音频格式
+var allowedCloneFormats = map[string]bool{
+	"mp3": true, "wav": true, "m4a": true, "ogg": true, "webm": true,
+}
+
+// 上传参考音频克隆声音
+func CloneVoice(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(response.Unauthorized("用户未认证").Code, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		resp := response.BadRequest("参考音频上传失败: " + err.Error())
+		c.JSON(resp.Code, resp)
+		return
+	}
+	if file.Size > maxCloneSampleSize {
+		resp := response.BadRequest("参考音频不能超过10MB")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		resp := response.BadRequest("读取参考音频失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+	defer f.Close()
+
+	sample, err := io.ReadAll(f)
+	if err != nil {
+		resp := response.BadRequest("读取参考音频失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	// 按内容识别格式，不信任扩展名
+	format := service.DetectAudioFormat(sample)
+	if !allowedCloneFormats[format] {
+		resp := response.BadRequest("不支持的音频格式，仅支持 MP3、WAV、M4A、OGG、WEBM")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	voice, err := service.CloneVoice(c.Request.Context(), service.VoiceCloneRequest{
+		UserID:   userID.(uint),
+		Name:     c.PostForm("name"),
+		Language: c.PostForm("language"),
+		Gender:   c.PostForm("gender"),
+		Sample:   sample,
+		Format:   format,
+	})
+	if err != nil {
+		var resp *response.Response
+		switch {
+		case errors.Is(err, service.ErrInvalidCloneRequest):
+			resp = response.BadRequest(err.Error())
+		case errors.Is(err, service.ErrVoiceCloneLimit):
+			resp = response.Forbidden(err.Error())
+		case errors.Is(err, service.ErrVoiceCloneFailed):
+			resp = response.InternalError(err.Error())
+		default:
+			log.Printf("保存克隆声音失败: %v", err)
+			resp = response.InternalError("声音克隆失败")
+		}
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.SuccessWithMessage("声音克隆成功", voice)
+	c.JSON(resp.Code, resp)
+}
+
+// 获取当前用户克隆的声音
+func ListClonedVoices(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(response.Unauthorized("用户未认证").Code, response.Unauthorized("用户未认证"))
+		return
+	}
+
+	voices, err := service.ListClonedVoices(userID.(uint))
+	if err != nil {
+		resp := response.InternalError("获取克隆声音失败")
+		c.JSON(resp.Code, resp)
+		return
+	}
+
+	resp := response.Success(voices)
+	c.JSON(resp.Code, resp)
+}
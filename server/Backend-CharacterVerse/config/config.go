@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -18,6 +20,52 @@ type Config struct {
 	RedisPort     string // Redis端口
 	RedisPassword string // Redis密码
 	RedisDB       int    // Redis数据库索引
+
+	AccessTokenTTLMinutes int // 访问令牌有效期（分钟）
+	RefreshTokenTTLHours  int // 刷新令牌有效期（小时）
+
+	AdminUserIDs []uint // 启动时授予管理员权限的用户ID
+
+	ASREngine       string // 语音识别引擎: qiniu 或 whisper
+	WhisperURL      string // 本地Whisper服务地址
+	WhisperLanguage string // Whisper识别语言，为空时自动检测
+
+	TTSDailyCharLimit   int // 每用户每日TTS字符额度
+	ASRDailySecondLimit int // 每用户每日ASR秒数额度
+
+	TTSCacheDir   string // TTS音频缓存目录，为空时禁用缓存
+	TTSCacheMaxMB int    // TTS音频缓存容量上限（MB）
+
+	VoiceCloneProvider   string // 声音克隆提供方: stub 或 http
+	VoiceCloneURL        string // HTTP克隆服务地址
+	VoiceCloneTTSURL     string // HTTP克隆服务的语音合成地址，克隆声音只能由该服务合成
+	VoiceCloneAPIKey     string // HTTP克隆服务密钥
+	VoiceCloneMaxPerUser int    // 每个用户最多可克隆的声音数
+
+	RankingRefreshMinutes int // 角色排行刷新周期（分钟），0表示禁用
+
+	AvatarWorkerCount    int // 头像生成并发数
+	AvatarJobMaxAttempts int // 头像生成任务最大尝试次数
+
+	ImageProvider       string // 图片生成提供方: dashscope 或 stub
+	DashScopeImageModel string // 百炼文生图模型
+
+	BlobStore             string // 对象存储: local、upload_service 或 s3
+	BlobLocalDir          string // 本地存储目录
+	BlobPublicURL         string // 本地存储对外访问前缀
+	UploadServiceURL      string // Upload_Voice_Service 地址
+	UploadTokenSecret     string // 上传服务令牌密钥，需与上传服务一致
+	UploadTokenTTLMinutes int    // 上传令牌有效期（分钟）
+	SignedURLTTLMinutes   int    // 文件访问链接有效期（分钟），适用于全部存储方式
+	S3Endpoint            string // S3兼容服务地址（如MinIO）
+	S3Region              string
+	S3Bucket              string
+	S3AccessKey           string
+	S3SecretKey           string
+	S3PublicURL           string // S3对外访问前缀，为空时使用 S3Endpoint/S3Bucket
+
+	StorageGCIntervalMinutes int // 无引用文件回收周期（分钟），0表示禁用
+	StorageGCGraceHours      int // 文件无引用后保留的时长（小时）
 }
 
 func LoadConfig() *Config {
@@ -35,6 +83,52 @@ func LoadConfig() *Config {
 		RedisPort:     getEnv("REDIS_PORT", "6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvInt("REDIS_DB", 0),
+
+		AccessTokenTTLMinutes: getEnvInt("ACCESS_TOKEN_TTL_MINUTES", 15),
+		RefreshTokenTTLHours:  getEnvInt("REFRESH_TOKEN_TTL_HOURS", 720),
+
+		AdminUserIDs: getEnvUintList("ADMIN_USER_IDS"),
+
+		ASREngine:       getEnv("ASR_ENGINE", "qiniu"),
+		WhisperURL:      getEnv("WHISPER_URL", "http://127.0.0.1:8178/inference"),
+		WhisperLanguage: getEnv("WHISPER_LANGUAGE", ""),
+
+		TTSDailyCharLimit:   getEnvInt("TTS_DAILY_CHAR_LIMIT", 5000),
+		ASRDailySecondLimit: getEnvInt("ASR_DAILY_SECOND_LIMIT", 600),
+
+		TTSCacheDir:   getEnv("TTS_CACHE_DIR", "./cache/tts"),
+		TTSCacheMaxMB: getEnvInt("TTS_CACHE_MAX_MB", 512),
+
+		VoiceCloneProvider:   getEnv("VOICE_CLONE_PROVIDER", "stub"),
+		VoiceCloneURL:        getEnv("VOICE_CLONE_URL", ""),
+		VoiceCloneTTSURL:     getEnv("VOICE_CLONE_TTS_URL", ""),
+		VoiceCloneAPIKey:     getEnv("VOICE_CLONE_API_KEY", ""),
+		VoiceCloneMaxPerUser: getEnvInt("VOICE_CLONE_MAX_PER_USER", 5),
+
+		RankingRefreshMinutes: getEnvInt("RANKING_REFRESH_MINUTES", 10),
+
+		AvatarWorkerCount:    getEnvInt("AVATAR_WORKER_COUNT", 2),
+		AvatarJobMaxAttempts: getEnvInt("AVATAR_JOB_MAX_ATTEMPTS", 5),
+
+		ImageProvider:       getEnv("IMAGE_PROVIDER", "dashscope"),
+		DashScopeImageModel: getEnv("DASHSCOPE_IMAGE_MODEL", "wan2.2-t2i-flash"),
+
+		BlobStore:             getEnv("BLOB_STORE", "upload_service"),
+		BlobLocalDir:          getEnv("BLOB_LOCAL_DIR", "./uploads"),
+		BlobPublicURL:         getEnv("BLOB_PUBLIC_URL", "http://localhost:8080/uploads"),
+		UploadServiceURL:      getEnv("UPLOAD_SERVICE_URL", "https://ai.mcell.top"),
+		UploadTokenSecret:     getEnv("UPLOAD_TOKEN_SECRET", ""),
+		UploadTokenTTLMinutes: getEnvInt("UPLOAD_TOKEN_TTL_MINUTES", 10),
+		SignedURLTTLMinutes:   getEnvInt("SIGNED_URL_TTL_MINUTES", 60),
+		S3Endpoint:            getEnv("S3_ENDPOINT", "http://127.0.0.1:9000"),
+		S3Region:              getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:              getEnv("S3_BUCKET", "character-verse"),
+		S3AccessKey:           getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:           getEnv("S3_SECRET_KEY", ""),
+		S3PublicURL:           getEnv("S3_PUBLIC_URL", ""),
+
+		StorageGCIntervalMinutes: getEnvInt("STORAGE_GC_INTERVAL_MINUTES", 60),
+		StorageGCGraceHours:      getEnvInt("STORAGE_GC_GRACE_HOURS", 72),
 	}
 }
 
@@ -45,6 +139,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// 读取逗号分隔的ID列表，忽略无法解析的项
+func getEnvUintList(key string) []uint {
+	var ids []uint
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value, exists := os.LookupEnv(key); exists {
 		var intValue int
@@ -2,14 +2,29 @@ package database
 
 import (
 	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
 	"errors"
+
+	"gorm.io/gorm"
 )
 
+// ErrRoleNotFound 角色不存在或无权访问，可用 errors.Is 与 gorm.ErrRecordNotFound 匹配
+var ErrRoleNotFound error = notFoundError("角色不存在")
+
+type notFoundError string
+
+func (e notFoundError) Error() string { return string(e) }
+
+func (e notFoundError) Unwrap() error { return gorm.ErrRecordNotFound }
+
 func GetRoleByID(roleID uint) (*model.Role, error) {
 	var role model.Role
 	result := DB.First(&role, roleID)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, ErrRoleNotFound
+	}
 	if result.Error != nil {
-		return nil, errors.New("角色不存在")
+		return nil, result.Error
 	}
 	return &role, nil
 }
@@ -42,7 +57,7 @@ func SaveChatMessage(userID, roleID uint, userMessage, messageType, voiceURL, ai
 		Message:     userMessage,
 		IsUser:      true,
 		MessageType: messageType,
-		VoiceURL:    voiceURL,
+		VoiceURL:    voiceRef(voiceURL),
 		ASRText:     userMessage, // 对于语音消息，ASRText是识别后的文本
 	}
 
@@ -53,7 +68,7 @@ func SaveChatMessage(userID, roleID uint, userMessage, messageType, voiceURL, ai
 		Message:     aiResponse,
 		IsUser:      false,
 		MessageType: messageType,
-		VoiceURL:    voiceURL,
+		VoiceURL:    voiceRef(voiceURL),
 		ASRText:     aiResponse,
 	}
 
@@ -77,35 +92,42 @@ func SaveUserMessage(userID, roleID uint, message, messageType, voiceURL string)
 		Message:     message,
 		IsUser:      true,
 		MessageType: messageType,
-		VoiceURL:    voiceURL,
+		VoiceURL:    voiceRef(voiceURL),
 		ASRText:     message,
 	}
 	return DB.Create(&history).Error
 }
 
 // 保存AI文本消息
-func SaveAITextMessage(userID, roleID uint, message string) error {
+func SaveAITextMessage(userID, roleID uint, message string, roleRevision int) error {
 	history := model.ChatHistory{
-		UserID:      userID,
-		RoleID:      roleID,
-		Message:     message,
-		IsUser:      false,
-		MessageType: "text",
-		ASRText:     message,
+		UserID:       userID,
+		RoleID:       roleID,
+		Message:      message,
+		IsUser:       false,
+		MessageType:  "text",
+		ASRText:      message,
+		RoleRevision: roleRevision,
 	}
 	return DB.Create(&history).Error
 }
 
 // 保存AI语音消息
-func SaveAIVoiceMessage(userID, roleID uint, asrText, voiceURL string) error {
+func SaveAIVoiceMessage(userID, roleID uint, asrText, voiceURL string, roleRevision int) error {
 	history := model.ChatHistory{
-		UserID:      userID,
-		RoleID:      roleID,
-		Message:     voiceURL, // 存储语音URL
-		IsUser:      false,
-		MessageType: "voice",
-		VoiceURL:    voiceURL,
-		ASRText:     asrText,
+		UserID:       userID,
+		RoleID:       roleID,
+		Message:      string(voiceRef(voiceURL)), // 存储语音对象键
+		IsUser:       false,
+		MessageType:  "voice",
+		VoiceURL:     voiceRef(voiceURL),
+		ASRText:      asrText,
+		RoleRevision: roleRevision,
 	}
 	return DB.Create(&history).Error
 }
+
+// 语音文件引用：本存储生成的URL统一保存为对象键
+func voiceRef(voiceURL string) model.BlobRef {
+	return model.BlobRef(storage.NormalizeRef(voiceURL))
+}
@@ -27,6 +27,11 @@ func InitDB() {
 		panic(fmt.Sprintf("failed to connect database: %v", err))
 	}
 
+	// 使用自定义关联表记录角色标签
+	if err := DB.SetupJoinTable(&model.Role{}, "Tags", &model.RoleTag{}); err != nil {
+		panic(fmt.Sprintf("failed to setup role tags: %v", err))
+	}
+
 	// 自动迁移模型
 	DB.AutoMigrate(
 		&model.User{},
@@ -34,6 +39,33 @@ func InitDB() {
 		&model.ChatHistory{},
 		&model.UserRoleHistory{},
 		&model.VoiceChatHistory{},
+		&model.Voice{},
+		&model.RoleLike{},
+		&model.RoleFavorite{},
+		&model.RoleRanking{},
+		&model.RoleSearchDocument{},
+		&model.Tag{},
+		&model.RoleTag{},
+		&model.RoleRevision{},
+		&model.AvatarJob{},
+		&model.AvatarCandidate{},
+		&model.StoredObject{},
+		&model.RefreshToken{},
 	)
 
+	// 初始化声音目录
+	if err := SeedVoices(); err != nil {
+		panic(fmt.Sprintf("failed to seed voices: %v", err))
+	}
+
+	// 初始化官方标签
+	if err := SeedTags(); err != nil {
+		panic(fmt.Sprintf("failed to seed tags: %v", err))
+	}
+
+	// 授予配置中的用户管理员权限
+	if err := SeedAdmins(cfg.AdminUserIDs); err != nil {
+		panic(fmt.Sprintf("failed to seed admins: %v", err))
+	}
+
 }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"Backend-CharacterVerse/model"
+
+	"gorm.io/gorm"
+)
+
+// SeedTags 写入官方分类标签，并为尚无标签关联的角色补建主分类关联
+func SeedTags() error {
+	for _, name := range model.ValidRoleTags {
+		tag := model.Tag{Name: name, Official: true}
+		if err := DB.Where("name = ?", name).
+			Attrs(tag).
+			FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		if !tag.Official {
+			if err := DB.Model(&tag).Update("official", true).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	err := DB.Exec(`INSERT INTO role_tags (role_id, tag_id, created_at)
+		SELECT roles.id, tags.id, NOW() FROM roles
+		JOIN tags ON tags.name = roles.tag
+		WHERE roles.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM role_tags WHERE role_tags.role_id = roles.id)`).Error
+	if err != nil {
+		return err
+	}
+
+	return RefreshTagUsage(DB, nil)
+}
+
+// RefreshTagUsage 重新统计标签使用次数（只计公开角色），tagIDs为空时统计全部标签
+func RefreshTagUsage(tx *gorm.DB, tagIDs []uint) error {
+	query := tx.Model(&model.Tag{})
+	if tagIDs != nil {
+		if len(tagIDs) == 0 {
+			return nil
+		}
+		query = query.Where("id IN ?", tagIDs)
+	} else {
+		query = query.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	return query.UpdateColumn("usage_count", gorm.Expr(
+		"(SELECT COUNT(*) FROM role_tags JOIN roles ON roles.id = role_tags.role_id AND roles.deleted_at IS NULL AND roles.visibility = ? WHERE role_tags.tag_id = tags.id)",
+		model.VisibilityPublic,
+	)).Error
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"Backend-CharacterVerse/model"
+	"log"
+)
+
+// SeedAdmins 为指定用户授予管理员权限，不会撤销其他用户已有的权限
+func SeedAdmins(userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	result := DB.Model(&model.User{}).Where("id IN ? AND is_admin = ?", userIDs, false).Update("is_admin", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已授予管理员权限: 用户数=%d", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"Backend-CharacterVerse/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// SeedVoices 将内置声音写入声音目录（已存在的不覆盖，保留管理员的修改）
+func SeedVoices() error {
+	for _, info := range model.GetVoiceList() {
+		voice := info.ToVoice()
+		if err := DB.Where("voice_type = ?", voice.VoiceType).
+			Attrs(voice).
+			FirstOrCreate(&voice).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetVoiceByType 根据声音类型标识获取声音
+func GetVoiceByType(voiceType string) (*model.Voice, error) {
+	var voice model.Voice
+	result := DB.Where("voice_type = ?", voiceType).First(&voice)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("声音类型不存在")
+		}
+		return nil, result.Error
+	}
+	return &voice, nil
+}
+
+// VoiceFilter 声音目录查询条件，零值表示不过滤
+type VoiceFilter struct {
+	Category        string
+	Language        string
+	IncludeDisabled bool // 是否包含已禁用的声音
+	AllOwners       bool // 是否包含所有用户的私有声音（管理员使用）
+	OwnerID         uint // 额外包含该用户的私有声音
+}
+
+// ListVoices 按条件查询声音目录
+func ListVoices(filter VoiceFilter) ([]model.Voice, error) {
+	query := DB.Model(&model.Voice{})
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Language != "" {
+		query = query.Where("language = ?", filter.Language)
+	}
+	if !filter.IncludeDisabled {
+		query = query.Where("enabled = ?", true)
+	}
+	if !filter.AllOwners {
+		query = query.Where("owner_id IN ?", []uint{0, filter.OwnerID})
+	}
+
+	var voices []model.Voice
+	if err := query.Order("id ASC").Find(&voices).Error; err != nil {
+		return nil, err
+	}
+	return voices, nil
+}
+
+// CountVoicesByOwner 统计用户的私有声音数量
+func CountVoicesByOwner(ownerID uint) (int64, error) {
+	var count int64
+	err := DB.Model(&model.Voice{}).Where("owner_id = ?", ownerID).Count(&count).Error
+	return count, err
+}
@@ -5,6 +5,8 @@ import (
 	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/middleware"
 	"Backend-CharacterVerse/router"
+	"Backend-CharacterVerse/service"
+	"Backend-CharacterVerse/storage"
 	"fmt"
 	"log"
 
@@ -12,7 +14,13 @@ import (
 )
 
 func main() {
-	config.LoadConfig()
+	cfg := config.LoadConfig()
+
+	// 初始化对象存储，配置有误时直接退出
+	if err := storage.Init(cfg); err != nil {
+		log.Fatalf("对象存储初始化失败: %v", err)
+	}
+
 	// 初始化数据库
 	database.InitDB()
 
@@ -24,6 +32,18 @@ func main() {
 		database.CloseRedis()
 	}()
 
+	// 启动角色排行后台任务
+	service.StartRankingJob()
+
+	// 启动头像生成工作池
+	service.StartAvatarWorkers()
+
+	// 启动无引用文件回收任务
+	service.StartStorageGC()
+
+	// 补建角色搜索索引
+	go service.BackfillRoleSearchIndex()
+
 	// 创建Gin引擎
 	r := gin.Default()
 	r.Use(middleware.CorsMiddleware()) // 添加CORS中间件
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/utils/response"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 要求当前用户为管理员，需在 JWTAuth 之后使用
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, response.Unauthorized("用户未认证"))
+			c.Abort()
+			return
+		}
+
+		var user model.User
+		if err := database.DB.First(&user, userID.(uint)).Error; err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, response.Forbidden("需要管理员权限"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
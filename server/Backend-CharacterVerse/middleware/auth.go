@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"Backend-CharacterVerse/service"
 	"Backend-CharacterVerse/utils"
 	"Backend-CharacterVerse/utils/response"
 	"fmt"
@@ -8,7 +9,6 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 )
 
 func JWTAuth() gin.HandlerFunc {
@@ -38,6 +38,19 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// OptionalJWTAuth 可选认证：携带有效令牌时设置用户ID，否则按未登录继续处理
+func OptionalJWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString != "" {
+			if claims, err := utils.ParseToken(tokenString); err == nil && claims.UserID != 0 && !service.IsTokenRevoked(claims) {
+				c.Set("userID", claims.UserID)
+			}
+		}
+		c.Next()
+	}
+}
+
 // 判断是否为 WebSocket 升级请求
 func isWebSocketUpgrade(r *http.Request) bool {
 	return strings.ToLower(r.Header.Get("Connection")) == "upgrade" &&
@@ -59,24 +72,27 @@ func handleWebSocketAuth(c *gin.Context) {
 
 // 处理令牌验证的公共逻辑
 func processToken(c *gin.Context, tokenString string) {
-	token, err := utils.ParseToken(tokenString)
-	if err != nil || !token.Valid {
+	claims, err := utils.ParseToken(tokenString)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, response.Unauthorized("无效的认证令牌"))
 		c.Abort()
 		return
 	}
+	if claims.UserID == 0 {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("令牌缺少用户ID声明"))
+		c.Abort()
+		return
+	}
 
-	// 将用户ID存入上下文
-	if claims, ok := token.Claims.(jwt.MapClaims); ok {
-		if userID, exists := claims["user_id"]; exists {
-			if id, ok := userID.(float64); ok {
-				c.Set("userID", uint(id))
-				c.Next()
-				return
-			}
-		}
+	// 已注销的会话签发的令牌立即失效
+	if service.IsTokenRevoked(claims) {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("认证令牌已失效，请重新登录"))
+		c.Abort()
+		return
 	}
 
-	c.JSON(http.StatusUnauthorized, response.Unauthorized("令牌缺少用户ID声明"))
-	c.Abort()
+	// 将用户ID和会话ID存入上下文
+	c.Set("userID", claims.UserID)
+	c.Set("sessionID", claims.SessionID)
+	c.Next()
 }
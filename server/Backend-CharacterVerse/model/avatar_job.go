@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// 头像生成任务状态
+const (
+	AvatarJobPending   = "pending"
+	AvatarJobRunning   = "running"
+	AvatarJobSucceeded = "succeeded"
+	AvatarJobFailed    = "failed"
+	AvatarJobCancelled = "cancelled" // 用户上传或选定了头像，未完成的任务不再执行
+)
+
+// AvatarJob 头像生成任务，持久化以便重启后继续处理
+type AvatarJob struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	RoleID      uint       `gorm:"not null;index" json:"role_id"`
+	Status      string     `gorm:"size:20;not null;default:'pending';index:idx_avatar_job_due" json:"status"`
+	Prompt      string     `gorm:"type:text;not null" json:"-"`
+	Negative    string     `gorm:"type:text" json:"-"`                                   // 反向提示词
+	Style       string     `gorm:"size:30;not null;default:''" json:"style"`             // 风格预设
+	Candidates  int        `gorm:"not null;default:1" json:"candidates"`                 // 候选图片数，大于1时由创建者挑选
+	ImageURLs   []string   `gorm:"serializer:json;type:text" json:"-"`                   // 已生成但尚未处理的图片地址，重试时跳过生成
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`                   // 已尝试次数
+	MaxAttempts int        `gorm:"not null;default:5" json:"max_attempts"`               // 最大尝试次数
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`                // 最近一次失败原因
+	NextRunAt   time.Time  `gorm:"not null;index:idx_avatar_job_due" json:"next_run_at"` // 下次可执行时间（退避）
+	StartedAt   *time.Time `json:"started_at"`                                           // 最近一次开始执行时间
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IsActive 任务是否仍在排队或执行中
+func (j *AvatarJob) IsActive() bool {
+	return j.Status == AvatarJobPending || j.Status == AvatarJobRunning
+}
+
+// AvatarCandidate 头像候选图，由创建者挑选后设为头像
+type AvatarCandidate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JobID     uint      `gorm:"not null;index" json:"job_id"`
+	RoleID    uint      `gorm:"not null;index" json:"role_id"`
+	URL       BlobRef   `gorm:"size:255;not null" json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"Backend-CharacterVerse/storage"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// BlobRef 对象存储中的文件引用：数据库中保存对象键，序列化为JSON时解析为访问地址
+type BlobRef string
+
+// URL 文件的访问地址
+func (r BlobRef) URL() string {
+	return storage.ResolveURL(string(r))
+}
+
+func (r BlobRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.URL())
+}
+
+// UnmarshalJSON 将访问地址还原为对象键，保证缓存往返后仍保存对象键
+func (r *BlobRef) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*r = BlobRef(storage.NormalizeRef(value))
+	return nil
+}
+
+// BlobRefMap 多个文件引用（如头像各尺寸缩略图），数据库中以JSON保存对象键
+type BlobRefMap map[string]BlobRef
+
+func (m BlobRefMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	keys := make(map[string]string, len(m))
+	for k, v := range m {
+		keys[k] = string(v)
+	}
+	data, err := json.Marshal(keys)
+	return string(data), err
+}
+
+func (m *BlobRefMap) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("无法解析文件引用: %T", value)
+	}
+	if len(data) == 0 {
+		*m = nil
+		return nil
+	}
+
+	var keys map[string]string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	result := make(BlobRefMap, len(keys))
+	for k, v := range keys {
+		result[k] = BlobRef(v)
+	}
+	*m = result
+	return nil
+}
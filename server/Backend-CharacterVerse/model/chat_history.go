@@ -4,12 +4,13 @@ import "gorm.io/gorm"
 
 type ChatHistory struct {
 	gorm.Model
-	UserID       uint   `gorm:"index"` // 用户ID
-	RoleID       uint   `gorm:"index"` // 角色ID
-	Message      string // 消息内容
-	IsUser       bool   // 是否为用户消息
-	MessageType  string `gorm:"type:enum('text','voice');default:'text'"` // 消息类型
-	VoiceURL     string // 语音URL（如果是语音消息）
-	ASRText      string // 语音转文字后的文本（如果是语音消息）
-	ResponseType int    `gorm:"default:0"` // 回复类型: 0=文字, 1=语音, 2=随机
+	UserID       uint    `gorm:"index"` // 用户ID
+	RoleID       uint    `gorm:"index"` // 角色ID
+	Message      string  // 消息内容
+	IsUser       bool    // 是否为用户消息
+	MessageType  string  `gorm:"type:enum('text','voice');default:'text'"` // 消息类型
+	VoiceURL     BlobRef // 语音对象键（如果是语音消息）
+	ASRText      string  // 语音转文字后的文本（如果是语音消息）
+	ResponseType int     `gorm:"default:0"` // 回复类型: 0=文字, 1=语音, 2=随机
+	RoleRevision int     `gorm:"default:0"` // 生成AI回复时的角色版本号（用户消息为0）
 }
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RefreshToken 刷新令牌，只保存哈希；每次刷新后轮换为新令牌
+type RefreshToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	SessionID  string     `gorm:"size:32;not null;index" json:"session_id"` // 登录会话ID，同一会话轮换出的令牌共用
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	ReplacedBy uint       `gorm:"not null;default:0" json:"replaced_by"` // 轮换后的新令牌ID
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -4,8 +4,36 @@ import "gorm.io/gorm"
 
 // 分页查询参数
 type Pagination struct {
-	Page     int `form:"page" binding:"min=0"`     // 修改为 min=0
-	PageSize int `form:"pageSize" binding:"min=0"` // 修改为 min=0 并修正字段名
+	Page     int    `form:"page" binding:"min=0"`     // 修改为 min=0
+	PageSize int    `form:"pageSize" binding:"min=0"` // 修改为 min=0 并修正字段名
+	Sort     string `form:"sort"`                     // 排序方式
+}
+
+// 角色列表排序方式
+const (
+	SortNew       = "new"       // 最新创建
+	SortPopular   = "popular"   // 点赞数+收藏数
+	SortLikes     = "likes"     // 点赞数
+	SortFavorites = "favorites" // 收藏数
+	SortHot       = "hot"       // 近期热度（后台任务计算）
+	SortTrending  = "trending"  // 短期飙升（后台任务计算）
+	SortTop       = "top"       // 历史总榜：点赞数+收藏数
+)
+
+// ValidRoleSorts 有效的排序方式
+var ValidRoleSorts = []string{SortNew, SortHot, SortTrending, SortTop, SortPopular, SortLikes, SortFavorites}
+
+// IsValidRoleSort 检查排序方式是否有效，空值表示默认排序
+func IsValidRoleSort(sort string) bool {
+	if sort == "" {
+		return true
+	}
+	for _, s := range ValidRoleSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
 }
 
 // 分页查询结果
@@ -45,14 +73,69 @@ var ValidRoleTags = []string{
 	TagOriginal,
 }
 
+// 角色可见性
+const (
+	VisibilityPrivate  = "private"  // 仅创建者可见、可对话
+	VisibilityUnlisted = "unlisted" // 不出现在列表和搜索中，知道ID即可对话
+	VisibilityPublic   = "public"   // 所有人可见
+)
+
+// 有效可见性列表
+var ValidVisibilities = []string{VisibilityPrivate, VisibilityUnlisted, VisibilityPublic}
+
+// IsValidVisibility 检查可见性是否有效
+func IsValidVisibility(visibility string) bool {
+	for _, v := range ValidVisibilities {
+		if v == visibility {
+			return true
+		}
+	}
+	return false
+}
+
 type Role struct {
 	gorm.Model
-	Name        string `gorm:"size:100;not null" json:"name"`                  // 角色名称
-	Description string `gorm:"type:text;not null" json:"description"`          // 角色描述
-	UserID      uint   `gorm:"not null" json:"user_id"`                        // 关联的用户ID
-	Gender      string `gorm:"size:10;not null;default:'未知'" json:"gender"`    // 性别
-	Age         int    `gorm:"not null;default:0" json:"age"`                  // 年龄
-	VoiceType   string `gorm:"size:50;not null" json:"voice_type"`             // 声音类型标识
-	AvatarURL   string `gorm:"size:255;not null;default:''" json:"avatar_url"` // 头像URL
-	Tag         string `gorm:"size:50;not null;default:'原创角色'" json:"tag"`     // 新增：角色标签
+	Name        string  `gorm:"size:100;not null" json:"name"`                  // 角色名称
+	Description string  `gorm:"type:text;not null" json:"description"`          // 角色描述
+	UserID      uint    `gorm:"not null" json:"user_id"`                        // 关联的用户ID
+	Gender      string  `gorm:"size:10;not null;default:'未知'" json:"gender"`    // 性别
+	Age         int     `gorm:"not null;default:0" json:"age"`                  // 年龄
+	VoiceType   string  `gorm:"size:50;not null" json:"voice_type"`             // 声音类型标识
+	AvatarURL   BlobRef `gorm:"size:255;not null;default:''" json:"avatar_url"` // 头像对象键
+	Tag         string  `gorm:"size:50;not null;default:'原创角色'" json:"tag"`     // 新增：角色标签
+
+	VoiceSpeed   float64 `gorm:"not null;default:1" json:"voice_speed"`            // 语速倍率
+	VoicePitch   float64 `gorm:"not null;default:1" json:"voice_pitch"`            // 音调倍率
+	VoiceVolume  float64 `gorm:"not null;default:1" json:"voice_volume"`           // 音量倍率
+	VoiceEmotion string  `gorm:"size:30;not null;default:''" json:"voice_emotion"` // 语音情感/风格
+
+	Visibility string `gorm:"size:20;not null;default:'public';index" json:"visibility"` // 可见性
+
+	LikeCount     int64 `gorm:"not null;default:0;index" json:"like_count"`     // 点赞数
+	FavoriteCount int64 `gorm:"not null;default:0;index" json:"favorite_count"` // 收藏数
+
+	Tags []Tag `gorm:"many2many:role_tags" json:"tags,omitempty"` // 全部标签（含主分类和自定义标签）
+
+	ForkedFromID *uint `gorm:"index" json:"forked_from_id"`             // 派生来源角色ID
+	ForkCount    int64 `gorm:"not null;default:0" json:"fork_count"`    // 被派生次数
+	AllowFork    bool  `gorm:"not null;default:true" json:"allow_fork"` // 是否允许他人派生
+
+	Revision int `gorm:"not null;default:1" json:"revision"` // 当前版本号，每次修改递增
+
+	AvatarThumbnails BlobRefMap `gorm:"type:text" json:"avatar_thumbnails,omitempty"` // 头像缩略图，边长 -> 对象键
+}
+
+// CanBeAccessedBy 判断用户能否查看和对话（私有角色仅创建者可访问）
+func (r *Role) CanBeAccessedBy(userID uint) bool {
+	return r.Visibility != VisibilityPrivate || r.UserID == userID
+}
+
+// GetVoiceParams 获取角色的语音参数，未设置的项使用默认值
+func (r *Role) GetVoiceParams() VoiceParams {
+	return DefaultVoiceParams().Merge(&VoiceParams{
+		Speed:   r.VoiceSpeed,
+		Pitch:   r.VoicePitch,
+		Volume:  r.VoiceVolume,
+		Emotion: r.VoiceEmotion,
+	})
 }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// RoleLike 用户点赞角色记录
+type RoleLike struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_like_user_role" json:"user_id"`
+	RoleID    uint      `gorm:"not null;uniqueIndex:idx_like_user_role;index" json:"role_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RoleFavorite 用户收藏角色记录
+type RoleFavorite struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_favorite_user_role" json:"user_id"`
+	RoleID    uint      `gorm:"not null;uniqueIndex:idx_favorite_user_role;index" json:"role_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RoleRanking 角色热度排行，由后台任务定期计算
+type RoleRanking struct {
+	RoleID        uint      `gorm:"primaryKey;autoIncrement:false" json:"role_id"`
+	HotScore      float64   `gorm:"index" json:"hot_score"`      // 热门分：较长窗口、较慢衰减
+	TrendingScore float64   `gorm:"index" json:"trending_score"` // 飙升分：较短窗口、较快衰减
+	MessageCount  int64     `json:"message_count"`               // 统计窗口内用户消息数
+	UniqueUsers   int64     `json:"unique_users"`                // 统计窗口内独立用户数
+	RecentLikes   int64     `json:"recent_likes"`                // 统计窗口内新增点赞数
+	UpdatedAt     time.Time `json:"updated_at"`
+}
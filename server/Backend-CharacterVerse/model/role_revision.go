@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// RoleRevision 角色历史版本快照，每次修改前保存修改前的状态
+type RoleRevision struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	RoleID   uint `gorm:"not null;uniqueIndex:idx_role_revision" json:"role_id"`
+	Revision int  `gorm:"not null;uniqueIndex:idx_role_revision" json:"revision"` // 快照对应的版本号
+	EditorID uint `gorm:"not null" json:"editor_id"`                              // 产生下一版本的操作者
+
+	Name         string  `gorm:"size:100;not null" json:"name"`
+	Description  string  `gorm:"type:text;not null" json:"description"`
+	Gender       string  `gorm:"size:10;not null" json:"gender"`
+	Age          int     `gorm:"not null" json:"age"`
+	VoiceType    string  `gorm:"size:50;not null" json:"voice_type"`
+	Tag          string  `gorm:"size:50;not null" json:"tag"`
+	Tags         string  `gorm:"type:text" json:"tags"` // 全部标签，以逗号分隔
+	VoiceSpeed   float64 `gorm:"not null;default:1" json:"voice_speed"`
+	VoicePitch   float64 `gorm:"not null;default:1" json:"voice_pitch"`
+	VoiceVolume  float64 `gorm:"not null;default:1" json:"voice_volume"`
+	VoiceEmotion string  `gorm:"size:30;not null;default:''" json:"voice_emotion"`
+	Visibility   string  `gorm:"size:20;not null" json:"visibility"`
+	AllowFork    bool    `gorm:"not null" json:"allow_fork"`
+
+	CreatedAt time.Time `json:"created_at"` // 快照时间，即该版本被取代的时间
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RoleSearchDocument 角色搜索文档，冗余角色名称、标签、创建者和描述用于全文检索
+// 全文索引使用MySQL ngram分词器以支持中文
+type RoleSearchDocument struct {
+	RoleID      uint   `gorm:"primaryKey;autoIncrement:false"`
+	Name        string `gorm:"size:100;not null;index:idx_role_search_name,class:FULLTEXT,option:WITH PARSER ngram;index:idx_role_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Tag         string `gorm:"size:50;not null;index:idx_role_search_tag,class:FULLTEXT,option:WITH PARSER ngram;index:idx_role_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Creator     string `gorm:"size:50;not null;index:idx_role_search_creator,class:FULLTEXT,option:WITH PARSER ngram;index:idx_role_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	Description string `gorm:"type:text;not null;index:idx_role_search_description,class:FULLTEXT,option:WITH PARSER ngram;index:idx_role_search_all,class:FULLTEXT,option:WITH PARSER ngram"`
+	NamePinyin  string `gorm:"size:255;not null;default:'';index:idx_role_search_pinyin,class:FULLTEXT,option:WITH PARSER ngram;index:idx_role_search_all,class:FULLTEXT,option:WITH PARSER ngram"` // 名称拼音首字母
+	UpdatedAt   time.Time
+}
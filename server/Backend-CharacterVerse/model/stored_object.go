@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// 存储对象类型
+const (
+	ObjectKindAvatar          = "avatar"           // 角色头像及缩略图
+	ObjectKindAvatarCandidate = "avatar_candidate" // 头像候选图
+	ObjectKindTTS             = "tts"              // 合成语音
+	ObjectKindVoice           = "voice"            // 用户语音消息
+	ObjectKindASR             = "asr"              // 识别时临时上传的音频
+	ObjectKindVoiceSample     = "voice_sample"     // 克隆声音的参考音频
+)
+
+// StoredObject 后端写入对象存储的文件，用于追踪引用并回收无人引用的文件
+type StoredObject struct {
+	ID                uint       `gorm:"primaryKey" json:"id"`
+	Key               string     `gorm:"size:255;not null;uniqueIndex" json:"key"`
+	Kind              string     `gorm:"size:30;not null;index" json:"kind"`
+	OwnerID           uint       `gorm:"not null;default:0;index" json:"owner_id"` // 所属用户，0表示系统
+	UploaderID        uint       `gorm:"not null;default:0" json:"uploader_id"`    // 计入存储配额的上传者，0表示系统
+	Size              int64      `gorm:"not null;default:0" json:"size"`
+	UnreferencedSince *time.Time `gorm:"index" json:"unreferenced_since"` // 最近一次回收扫描发现无引用的时间
+	CreatedAt         time.Time  `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// 标签过滤模式
+const (
+	TagModeAny = "or"  // 命中任一标签
+	TagModeAll = "and" // 命中全部标签
+)
+
+// Tag 角色标签，包含官方分类和用户自定义标签
+type Tag struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Name       string    `gorm:"size:30;not null;uniqueIndex" json:"name"`     // 规范化后的标签名
+	Official   bool      `gorm:"not null;default:false;index" json:"official"` // 是否为官方分类
+	UsageCount int64     `gorm:"not null;default:0;index" json:"usage_count"`  // 使用该标签的角色数
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RoleTag 角色与标签的关联
+type RoleTag struct {
+	RoleID    uint `gorm:"primaryKey"`
+	TagID     uint `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+}
+
+// RoleFilter 角色列表的标签过滤条件
+type RoleFilter struct {
+	Tags    []string // 规范化后的标签名，为空表示不过滤
+	TagMode string   // or 或 and，默认 or
+}
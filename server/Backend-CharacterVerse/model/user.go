@@ -6,4 +6,5 @@ type User struct {
 	gorm.Model
 	Username string `gorm:"uniqueIndex;size:50" json:"username"`
 	Password string `gorm:"size:100" json:"-"`
+	IsAdmin  bool   `gorm:"not null;default:false" json:"is_admin"` // 是否为管理员
 }
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
 // 声音类型常量
 const (
 	VoiceSweetTeacher           = "qiniu_zh_female_tmjxxy"    // 甜美教学小源
@@ -42,6 +49,99 @@ const (
 	VoiceGeniusBoy              = "qiniu_zh_male_tcsnsf"      // 天才少年示范
 )
 
+// 声音提供方
+const (
+	VoiceProviderQiniu = "qiniu"
+)
+
+// 声音分类：用户克隆的声音
+const VoiceCategoryCloned = "克隆音色"
+
+// 声音语言
+const (
+	VoiceLanguageChinese = "zh"
+	VoiceLanguageEnglish = "en"
+	VoiceLanguageMulti   = "multi"
+)
+
+// 声音性别
+const (
+	VoiceGenderMale    = "male"
+	VoiceGenderFemale  = "female"
+	VoiceGenderUnknown = "unknown"
+)
+
+// Voice 数据库中的声音目录
+type Voice struct {
+	gorm.Model
+	VoiceType string  `gorm:"size:100;uniqueIndex;not null" json:"voice_type"`     // 声音类型标识
+	VoiceName string  `gorm:"size:100;not null" json:"voice_name"`                 // 声音名称
+	URL       BlobRef `gorm:"size:255;not null;default:''" json:"sample_url"`      // 试听音频（对象键或外部URL）
+	Category  string  `gorm:"size:50;not null;default:'';index" json:"category"`   // 分类
+	Provider  string  `gorm:"size:30;not null;default:'qiniu'" json:"provider"`    // 提供方
+	Language  string  `gorm:"size:20;not null;default:'zh';index" json:"language"` // 语言
+	Gender    string  `gorm:"size:10;not null;default:'unknown'" json:"gender"`    // 性别
+	Enabled   bool    `gorm:"not null;default:true" json:"enabled"`                // 是否启用
+
+	OwnerID         uint   `gorm:"not null;default:0;index" json:"owner_id"` // 所属用户，0表示公共声音
+	ProviderVoiceID string `gorm:"size:100;not null;default:''" json:"-"`    // 提供方侧的声音ID，为空时与VoiceType相同
+}
+
+// IsPrivate 是否为用户私有声音
+func (v *Voice) IsPrivate() bool {
+	return v.OwnerID != 0
+}
+
+// UsableBy 判断用户能否使用该声音
+func (v *Voice) UsableBy(userID uint) bool {
+	return v.Enabled && (v.OwnerID == 0 || v.OwnerID == userID)
+}
+
+// TTSVoiceType 合成时实际使用的声音标识
+func (v *Voice) TTSVoiceType() string {
+	if v.ProviderVoiceID != "" {
+		return v.ProviderVoiceID
+	}
+	return v.VoiceType
+}
+
+// ParseQiniuVoiceType 从七牛云声音标识(qiniu_{语言}_{性别}_xxx)中解析语言和性别
+func ParseQiniuVoiceType(voiceType string) (language, gender string) {
+	language, gender = VoiceLanguageChinese, VoiceGenderUnknown
+	parts := strings.Split(voiceType, "_")
+	if len(parts) < 3 {
+		return
+	}
+	switch parts[1] {
+	case "en":
+		language = VoiceLanguageEnglish
+	case "multi":
+		language = VoiceLanguageMulti
+	}
+	switch parts[2] {
+	case "male":
+		gender = VoiceGenderMale
+	case "female":
+		gender = VoiceGenderFemale
+	}
+	return
+}
+
+// ToVoice 将内置声音信息转换为数据库记录
+func (v VoiceInfo) ToVoice() Voice {
+	language, gender := ParseQiniuVoiceType(v.VoiceType)
+	return Voice{
+		VoiceType: v.VoiceType,
+		VoiceName: v.VoiceName,
+		URL:       BlobRef(v.URL),
+		Category:  v.Category,
+		Provider:  VoiceProviderQiniu,
+		Language:  language,
+		Gender:    gender,
+		Enabled:   true,
+	}
+}
+
 // VoiceInfo 声音类型信息结构
 type VoiceInfo struct {
 	VoiceName string `json:"voice_name"`
@@ -50,246 +150,338 @@ type VoiceInfo struct {
 	Category  string `json:"category"`
 }
 
-// GetVoiceList 获取所有可用声音类型
+// GetVoiceList 获取内置声音类型列表（用于初始化数据库中的声音目录）
 func GetVoiceList() []VoiceInfo {
-	return []VoiceInfo{
-		{
-			VoiceName: "甜美教学小源",
-			VoiceType: VoiceSweetTeacher,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_tmjxxy.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "校园清新学姐",
-			VoiceType: VoiceCampusSister,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_xyqxxj.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "邻家辅导学长",
-			VoiceType: VoiceTutorBrother,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_ljfdxz.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "邻家辅导学姐",
-			VoiceType: VoiceTutorSister,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_ljfdxx.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "温婉学科讲师",
-			VoiceType: VoiceGentleTeacher,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_wwxkjx.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "率真校园向导",
-			VoiceType: VoiceCampusGuide,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_szxyxd.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "干练课堂思思",
-			VoiceType: VoiceClassroomSisi,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_glktss.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "温和学科小哥",
-			VoiceType: VoiceSubjectGuy,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_whxkxg.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "温暖沉稳学长",
-			VoiceType: VoiceWarmSenior,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_wncwxz.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "开朗教学督导",
-			VoiceType: VoiceCheerfulSupervisor,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_kljxdd.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "渊博学科男教师",
-			VoiceType: VoiceKnowledgeableTeacher,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_ybxknjs.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "火力少年凯凯",
-			VoiceType: VoiceEnergeticKai,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_hlsnkk.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "通用阳光讲师",
-			VoiceType: VoiceSunnyLecturer,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_tyygjs.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "知性教学女教师",
-			VoiceType: VoiceIntellectualTeacher,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_zxjxnjs.mp3",
-			Category:  "传统音色",
-		},
-		{
-			VoiceName: "澳洲英语女",
-			VoiceType: VoiceAussieEnglishFemale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_azyy.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "日西双语女1",
-			VoiceType: VoiceJapaneseSpanishFemale1,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_female_rxsyn1.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "日西双语男2",
-			VoiceType: VoiceJapaneseSpanishMale2,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_male_rxsyn2.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "英式英语男",
-			VoiceType: VoiceBritishEnglishMale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_ysyyn.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "英式英语女",
-			VoiceType: VoiceBritishEnglishFemale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_ysyyn.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "美式英语女",
-			VoiceType: VoiceAmericanEnglishFemale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_msyyn.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "美式英语男",
-			VoiceType: VoiceAmericanEnglishMale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_msyyn.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "澳洲英语男",
-			VoiceType: VoiceAussieEnglishMale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_azyyn.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "日西双语男1",
-			VoiceType: VoiceJapaneseSpanishMale1,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_male_rxsyn1.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "日西双语女2",
-			VoiceType: VoiceJapaneseSpanishFemale2,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_female_rxsyn2.mp3",
-			Category:  "双语音色",
-		},
-		{
-			VoiceName: "慈祥教学顾问",
-			VoiceType: VoiceKindlyAdvisor,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_cxjxgw.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "社区教育阿姨",
-			VoiceType: VoiceCommunityAuntie,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_sqjyay.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "动漫樱桃丸子",
-			VoiceType: VoiceAnimeSakura,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_dmytwz.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "少儿故事配音",
-			VoiceType: VoiceChildrenStoryFemale,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_segsby.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "轻松懒音绵宝",
-			VoiceType: VoiceRelaxedLazy,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_qslymb.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "活力率真萌仔",
-			VoiceType: VoiceEnergeticMeng,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_hllzmz.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "温婉课件配音",
-			VoiceType: VoiceGentleCourseware,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_wwkjby.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "儿童故事熊二",
-			VoiceType: VoiceChildrenStoryBear,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_etgsxe.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "古装剧教学版",
-			VoiceType: VoiceCostumeDrama,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_gzjjxb.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "磁性课件男声",
-			VoiceType: VoiceMagneticCourseware,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_cxkjns.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "趣味知识传播",
-			VoiceType: VoiceFunKnowledge,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_qwzscb.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "名著角色猴哥",
-			VoiceType: VoiceClassicMonkeyKing,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_mzjsxg.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "英语启蒙佩奇",
-			VoiceType: VoiceEnglishPeppa,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_yyqmpq.mp3",
-			Category:  "特殊音色",
-		},
-		{
-			VoiceName: "天才少年示范",
-			VoiceType: VoiceGeniusBoy,
-			URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_tcsnsf.mp3",
-			Category:  "特殊音色",
-		},
-	}
+	return builtinVoices
+}
+
+// 内置声音类型
+var builtinVoices = []VoiceInfo{
+	{
+		VoiceName: "甜美教学小源",
+		VoiceType: VoiceSweetTeacher,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_tmjxxy.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "校园清新学姐",
+		VoiceType: VoiceCampusSister,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_xyqxxj.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "邻家辅导学长",
+		VoiceType: VoiceTutorBrother,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_ljfdxz.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "邻家辅导学姐",
+		VoiceType: VoiceTutorSister,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_ljfdxx.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "温婉学科讲师",
+		VoiceType: VoiceGentleTeacher,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_wwxkjx.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "率真校园向导",
+		VoiceType: VoiceCampusGuide,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_szxyxd.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "干练课堂思思",
+		VoiceType: VoiceClassroomSisi,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_glktss.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "温和学科小哥",
+		VoiceType: VoiceSubjectGuy,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_whxkxg.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "温暖沉稳学长",
+		VoiceType: VoiceWarmSenior,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_wncwxz.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "开朗教学督导",
+		VoiceType: VoiceCheerfulSupervisor,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_kljxdd.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "渊博学科男教师",
+		VoiceType: VoiceKnowledgeableTeacher,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_ybxknjs.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "火力少年凯凯",
+		VoiceType: VoiceEnergeticKai,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_hlsnkk.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "通用阳光讲师",
+		VoiceType: VoiceSunnyLecturer,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_tyygjs.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "知性教学女教师",
+		VoiceType: VoiceIntellectualTeacher,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_zxjxnjs.mp3",
+		Category:  "传统音色",
+	},
+	{
+		VoiceName: "澳洲英语女",
+		VoiceType: VoiceAussieEnglishFemale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_azyy.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "日西双语女1",
+		VoiceType: VoiceJapaneseSpanishFemale1,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_female_rxsyn1.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "日西双语男2",
+		VoiceType: VoiceJapaneseSpanishMale2,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_male_rxsyn2.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "英式英语男",
+		VoiceType: VoiceBritishEnglishMale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_ysyyn.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "英式英语女",
+		VoiceType: VoiceBritishEnglishFemale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_ysyyn.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "美式英语女",
+		VoiceType: VoiceAmericanEnglishFemale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_female_msyyn.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "美式英语男",
+		VoiceType: VoiceAmericanEnglishMale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_msyyn.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "澳洲英语男",
+		VoiceType: VoiceAussieEnglishMale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_en_male_azyyn.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "日西双语男1",
+		VoiceType: VoiceJapaneseSpanishMale1,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_male_rxsyn1.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "日西双语女2",
+		VoiceType: VoiceJapaneseSpanishFemale2,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_multi_female_rxsyn2.mp3",
+		Category:  "双语音色",
+	},
+	{
+		VoiceName: "慈祥教学顾问",
+		VoiceType: VoiceKindlyAdvisor,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_cxjxgw.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "社区教育阿姨",
+		VoiceType: VoiceCommunityAuntie,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_sqjyay.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "动漫樱桃丸子",
+		VoiceType: VoiceAnimeSakura,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_dmytwz.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "少儿故事配音",
+		VoiceType: VoiceChildrenStoryFemale,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_segsby.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "轻松懒音绵宝",
+		VoiceType: VoiceRelaxedLazy,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_qslymb.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "活力率真萌仔",
+		VoiceType: VoiceEnergeticMeng,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_hllzmz.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "温婉课件配音",
+		VoiceType: VoiceGentleCourseware,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_wwkjby.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "儿童故事熊二",
+		VoiceType: VoiceChildrenStoryBear,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_etgsxe.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "古装剧教学版",
+		VoiceType: VoiceCostumeDrama,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_gzjjxb.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "磁性课件男声",
+		VoiceType: VoiceMagneticCourseware,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_cxkjns.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "趣味知识传播",
+		VoiceType: VoiceFunKnowledge,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_qwzscb.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "名著角色猴哥",
+		VoiceType: VoiceClassicMonkeyKing,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_mzjsxg.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "英语启蒙佩奇",
+		VoiceType: VoiceEnglishPeppa,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_female_yyqmpq.mp3",
+		Category:  "特殊音色",
+	},
+	{
+		VoiceName: "天才少年示范",
+		VoiceType: VoiceGeniusBoy,
+		URL:       "https://aitoken-public.qnaigc.com/ai-voice/qiniu_zh_male_tcsnsf.mp3",
+		Category:  "特殊音色",
+	},
+}
+
+// 语音情感/风格
+const (
+	EmotionNeutral   = "neutral"
+	EmotionHappy     = "happy"
+	EmotionSad       = "sad"
+	EmotionAngry     = "angry"
+	EmotionSurprised = "surprised"
+	EmotionFear      = "fear"
+	EmotionExcited   = "excited"
+	EmotionColdness  = "coldness"
+)
+
+// 有效情感列表，空字符串表示不指定
+var ValidVoiceEmotions = []string{
+	"",
+	EmotionNeutral,
+	EmotionHappy,
+	EmotionSad,
+	EmotionAngry,
+	EmotionSurprised,
+	EmotionFear,
+	EmotionExcited,
+	EmotionColdness,
 }
 
-// GetVoiceInfo 根据声音类型标识获取详细信息
-func GetVoiceInfo(voiceType string) (VoiceInfo, bool) {
-	for _, voice := range GetVoiceList() {
-		if voice.VoiceType == voiceType {
-			return voice, true
+// 语速、音调、音量的取值范围
+const (
+	MinVoiceRatio = 0.5
+	MaxVoiceRatio = 2.0
+)
+
+// VoiceParams 语音合成参数
+type VoiceParams struct {
+	Speed   float64 `json:"speed,omitempty"`   // 语速倍率
+	Pitch   float64 `json:"pitch,omitempty"`   // 音调倍率
+	Volume  float64 `json:"volume,omitempty"`  // 音量倍率
+	Emotion string  `json:"emotion,omitempty"` // 情感/风格
+}
+
+// DefaultVoiceParams 默认语音参数
+func DefaultVoiceParams() VoiceParams {
+	return VoiceParams{Speed: 1.0, Pitch: 1.0, Volume: 1.0}
+}
+
+// IsValidVoiceEmotion 检查情感是否有效
+func IsValidVoiceEmotion(emotion string) bool {
+	for _, e := range ValidVoiceEmotions {
+		if e == emotion {
+			return true
 		}
 	}
-	return VoiceInfo{}, false
+	return false
+}
+
+// ValidateVoiceRatio 检查语速/音调/音量倍率是否在有效范围内，0表示使用默认值
+func ValidateVoiceRatio(name string, value float64) error {
+	if value != 0 && (value < MinVoiceRatio || value > MaxVoiceRatio) {
+		return fmt.Errorf("%s必须在%.1f-%.1f之间", name, MinVoiceRatio, MaxVoiceRatio)
+	}
+	return nil
+}
+
+// Validate 校验语音参数
+func (p VoiceParams) Validate() error {
+	if err := ValidateVoiceRatio("语速", p.Speed); err != nil {
+		return err
+	}
+	if err := ValidateVoiceRatio("音调", p.Pitch); err != nil {
+		return err
+	}
+	if err := ValidateVoiceRatio("音量", p.Volume); err != nil {
+		return err
+	}
+	if !IsValidVoiceEmotion(p.Emotion) {
+		return fmt.Errorf("无效的语音情感，有效值为: %v", ValidVoiceEmotions[1:])
+	}
+	return nil
+}
+
+// Merge 用非零的覆盖值替换当前参数
+func (p VoiceParams) Merge(override *VoiceParams) VoiceParams {
+	if override == nil {
+		return p
+	}
+	if override.Speed != 0 {
+		p.Speed = override.Speed
+	}
+	if override.Pitch != 0 {
+		p.Pitch = override.Pitch
+	}
+	if override.Volume != 0 {
+		p.Volume = override.Volume
+	}
+	if override.Emotion != "" {
+		p.Emotion = override.Emotion
+	}
+	return p
 }
@@ -2,22 +2,42 @@ package router
 
 import (
 	"Backend-CharacterVerse/api"
+	"Backend-CharacterVerse/config"
 	"Backend-CharacterVerse/middleware"
+	"Backend-CharacterVerse/service"
+	"Backend-CharacterVerse/storage"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RouterInit(r *gin.Engine) {
+	// 使用本地对象存储时由后端校验签名后提供文件访问
+	if cfg := config.LoadConfig(); cfg.BlobStore == storage.BackendLocal {
+		staticPath := "/uploads"
+		if u, err := url.Parse(cfg.BlobPublicURL); err == nil && u.Path != "" && u.Path != "/" {
+			staticPath = strings.TrimRight(u.Path, "/")
+		}
+		r.GET(staticPath+"/*key", api.ServeLocalFile)
+		r.HEAD(staticPath+"/*key", api.ServeLocalFile)
+	}
+
 	// 公共路由
 	public := r.Group("/api")
 	{
 		public.POST("/user/register", api.Register)
 		public.POST("/user/login", api.Login)
+		public.POST("/user/refresh", api.RefreshToken)
 		public.GET("/voiceTypes", api.GetAllVoiceTypes)
 		roleGroup := public.Group("/role")
 		{
 			roleGroup.GET("/tag", api.GetRolesByTag)
 			roleGroup.GET("/search", api.SearchRoles)
+			roleGroup.GET("/tags", api.GetTags)
+			roleGroup.GET("/avatar/styles", api.GetAvatarStyles)
+			roleGroup.GET("/:role_id", middleware.OptionalJWTAuth(), api.GetRoleDetail)
+			roleGroup.GET("/:role_id/avatar/status", middleware.OptionalJWTAuth(), api.GetAvatarStatus)
 		}
 	}
 
@@ -25,6 +45,8 @@ func RouterInit(r *gin.Engine) {
 	auth := r.Group("/api")
 	auth.Use(middleware.JWTAuth())
 	{
+		auth.POST("/user/logout", api.Logout)
+		auth.POST("/user/logout/all", api.LogoutAll)
 		auth.GET("/ws/chat", api.ChatHandler)
 		auth.GET("/ws/voice_chat", api.VoiceChatHandler)
 		roleGroup := auth.Group("/role")
@@ -34,8 +56,42 @@ func RouterInit(r *gin.Engine) {
 			roleGroup.GET("/user", api.GetRolesByUsername)
 			roleGroup.DELETE("/:role_id", api.DeleteRole)
 			roleGroup.PUT("/:role_id", api.UpdateRole)
+			roleGroup.GET("/favorites", api.GetFavoriteRoles)
+			roleGroup.POST("/:role_id/like", api.LikeRole)
+			roleGroup.DELETE("/:role_id/like", api.UnlikeRole)
+			roleGroup.POST("/:role_id/favorite", api.FavoriteRole)
+			roleGroup.DELETE("/:role_id/favorite", api.UnfavoriteRole)
+			roleGroup.POST("/:role_id/fork", api.ForkRole)
+			roleGroup.POST("/:role_id/avatar", api.UploadRoleAvatar)
+			roleGroup.POST("/:role_id/avatar/regenerate", api.RegenerateAvatar)
+			roleGroup.GET("/:role_id/avatar/candidates", api.GetAvatarCandidates)
+			roleGroup.POST("/:role_id/avatar/candidates/:candidate_id/select", api.SelectAvatarCandidate)
+			roleGroup.GET("/:role_id/revisions", api.ListRoleRevisions)
+			roleGroup.GET("/:role_id/revisions/diff", api.DiffRoleRevisions)
+			roleGroup.GET("/:role_id/revisions/:revision", api.GetRoleRevision)
+			roleGroup.POST("/:role_id/revisions/:revision/restore", api.RestoreRoleRevision)
+		}
+
+		voiceGroup := auth.Group("/voice")
+		{
+			voiceGroup.POST("/tts", service.TTSHandler)
+			voiceGroup.POST("/asr", service.ASRHandler)
+			voiceGroup.POST("/clone", api.CloneVoice)
+			voiceGroup.GET("/clone", api.ListClonedVoices)
 		}
 
+		adminGroup := auth.Group("/admin")
+		adminGroup.Use(middleware.AdminAuth())
+		{
+			adminGroup.GET("/voices", api.ListAllVoices)
+			adminGroup.POST("/voices", api.AddVoice)
+			adminGroup.PUT("/voices/:voice_type", api.UpdateVoice)
+			adminGroup.GET("/storage/orphans", api.GetOrphanedObjects)
+			adminGroup.GET("/tts/cache/stats", service.TTSCacheStatsHandler)
+		}
+
+		auth.GET("/upload/token", api.GetUploadToken)
+
 		historyGroup := auth.Group("/history")
 		{
 			historyGroup.GET("/all", api.GetAllChatHistories)
@@ -1,19 +1,83 @@
 package service
 
 import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
+	"Backend-CharacterVerse/utils/response"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ASR引擎类型
+const (
+	ASREngineQiniu   = "qiniu"
+	ASREngineWhisper = "whisper"
+)
+
+// ASRInput 语音识别输入，URL与Data二选一
+type ASRInput struct {
+	URL    string // 本存储中的音频对象键或访问地址
+	Data   []byte // 原始音频数据
+	Format string // 音频格式，如 mp3, wav
+}
+
+// WordTimestamp 单词级时间戳（单位：秒）
+type WordTimestamp struct {
+	Word       string  `json:"word"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ASRResult 语音识别结果
+type ASRResult struct {
+	Text       string          `json:"text"`
+	Language   string          `json:"language,omitempty"`
+	Confidence float64         `json:"confidence,omitempty"` // 引擎未提供时为0
+	Duration   float64         `json:"duration"`             // 音频时长（秒）
+	Words      []WordTimestamp `json:"words,omitempty"`
+	Engine     string          `json:"engine"`
+}
+
+// SpeechRecognizer 语音识别引擎接口
+type SpeechRecognizer interface {
+	Name() string
+	Recognize(ctx context.Context, input ASRInput) (*ASRResult, error)
+}
+
+// NewSpeechRecognizer 根据配置创建语音识别引擎
+func NewSpeechRecognizer(cfg *config.Config) (SpeechRecognizer, error) {
+	switch cfg.ASREngine {
+	case "", ASREngineQiniu:
+		return &QiniuRecognizer{
+			APIKey: os.Getenv("QINIU_API_KEY"),
+			Client: &http.Client{Timeout: 60 * time.Second},
+		}, nil
+	case ASREngineWhisper:
+		return &WhisperRecognizer{
+			Endpoint: cfg.WhisperURL,
+			Language: cfg.WhisperLanguage,
+			Client:   &http.Client{Timeout: 120 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的ASR引擎: %s", cfg.ASREngine)
+	}
+}
+
 // 七牛云ASR请求结构体 (修正版)
 type QiniuASRRequest struct {
 	Model string `json:"model"`
@@ -40,72 +104,81 @@ type QiniuASRResponse struct {
 	} `json:"data"`
 }
 
-// RecognizeSpeech 调用七牛云ASR服务识别语音 (修复版)
-func RecognizeSpeech(audioURL string, format string) (string, error) {
-	apiKey := os.Getenv("QINIU_API_KEY")
-	if apiKey == "" {
-		return "", errors.New("未配置七牛云API密钥")
+// QiniuRecognizer 七牛云ASR实现（仅支持URL，原始数据会先上传）
+type QiniuRecognizer struct {
+	APIKey string
+	Client *http.Client
+}
+
+func (r *QiniuRecognizer) Name() string {
+	return ASREngineQiniu
+}
+
+func (r *QiniuRecognizer) Recognize(ctx context.Context, input ASRInput) (*ASRResult, error) {
+	if r.APIKey == "" {
+		return nil, errors.New("未配置七牛云API密钥")
+	}
+
+	// 七牛云只接受URL，原始音频需先上传
+	audioURL := input.URL
+	if audioURL == "" && len(input.Data) > 0 {
+		key, err := uploadAudio(ctx, model.ObjectKindASR, 0, input.Data, input.Format)
+		if err != nil {
+			return nil, fmt.Errorf("上传音频失败: %w", err)
+		}
+		audioURL = storage.ResolveURL(key)
 	}
 
 	// 验证音频URL
 	if audioURL == "" {
-		return "", errors.New("音频URL不能为空")
+		return nil, errors.New("音频URL不能为空")
 	}
 
 	// 设置默认音频格式
+	format := input.Format
 	if format == "" {
 		format = "mp3" // 默认格式
 	}
 
 	// 构造请求体 (修正版)
-	asrReq := QiniuASRRequest{
-		Model: "asr",
-		Audio: struct {
-			Format string `json:"format"`
-			URL    string `json:"url"`
-		}{
-			Format: format,
-			URL:    audioURL,
-		},
-	}
+	asrReq := QiniuASRRequest{Model: "asr"}
+	asrReq.Audio.Format = format
+	asrReq.Audio.URL = audioURL
 
 	jsonData, err := json.Marshal(asrReq)
 	if err != nil {
-		return "", fmt.Errorf("JSON序列化失败: %w", err)
+		return nil, fmt.Errorf("JSON序列化失败: %w", err)
 	}
 
 	// 调试日志：打印请求体
 	log.Printf("ASR请求体: %s", string(jsonData))
 
 	// 创建HTTP请求
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		ctx,
 		"POST",
 		"https://openai.qiniu.com/v1/voice/asr",
 		bytes.NewBuffer(jsonData),
 	)
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-	}
+	req.Header.Set("Authorization", "Bearer "+r.APIKey)
 
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := r.Client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("API请求失败: %w", err)
+		return nil, fmt.Errorf("API请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取完整响应体
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取响应体失败: %w", err)
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
 	}
 
 	// 检查响应状态
@@ -119,49 +192,146 @@ func RecognizeSpeech(audioURL string, format string) (string, error) {
 		}
 
 		if err := json.Unmarshal(bodyBytes, &errorResponse); err == nil {
-			return "", fmt.Errorf("ASR API错误: %s (类型: %s)",
+			return nil, fmt.Errorf("ASR API错误: %s (类型: %s)",
 				errorResponse.Error.Message,
 				errorResponse.Error.Type)
 		}
 
-		return "", fmt.Errorf("ASR API返回错误状态码: %d, 响应: %s",
+		return nil, fmt.Errorf("ASR API返回错误状态码: %d, 响应: %s",
 			resp.StatusCode, string(bodyBytes))
 	}
 
 	// 解析响应
 	var apiResponse QiniuASRResponse
 	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
-		return "", fmt.Errorf("解析API响应失败: %w", err)
+		return nil, fmt.Errorf("解析API响应失败: %w", err)
 	}
 
 	// 提取识别文本
-	if apiResponse.Data.Result.Text != "" {
-		return apiResponse.Data.Result.Text, nil
+	if apiResponse.Data.Result.Text == "" {
+		return nil, errors.New("未识别到有效文本")
 	}
 
-	return "", errors.New("未识别到有效文本")
+	// 七牛云不返回语言、置信度和时间戳，时长单位为毫秒
+	return &ASRResult{
+		Text:     apiResponse.Data.Result.Text,
+		Duration: float64(apiResponse.Data.AudioInfo.Duration) / 1000,
+		Engine:   r.Name(),
+	}, nil
 }
 
-// ASRHandler 处理ASR请求的API端点
+// RecognizeSpeech 使用配置的ASR引擎识别音频URL
+func RecognizeSpeech(audioURL string, format string) (*ASRResult, error) {
+	return RecognizeSpeechInput(context.Background(), ASRInput{URL: audioURL, Format: format})
+}
+
+// RecognizeSpeechInput 使用配置的ASR引擎识别语音（URL或原始数据）
+func RecognizeSpeechInput(ctx context.Context, input ASRInput) (*ASRResult, error) {
+	if input.URL == "" && len(input.Data) == 0 {
+		return nil, errors.New("音频URL和音频数据不能同时为空")
+	}
+	// 只识别本存储中的音频，避免服务端请求任意地址
+	if input.URL != "" {
+		key, err := storage.OwnedKey(input.URL)
+		if err != nil {
+			return nil, fmt.Errorf("无效的音频地址: %w", err)
+		}
+		input.URL = storage.ResolveURL(key)
+	}
+
+	recognizer, err := NewSpeechRecognizer(config.LoadConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	return recognizer.Recognize(ctx, input)
+}
+
+// 单次上传音频的大小上限
+const maxASRUploadSize = 20 << 20
+
+// ASRHandler 处理ASR请求的API端点，支持JSON(audio_url)或multipart文件上传
 func ASRHandler(c *gin.Context) {
-	var request struct {
-		AudioURL string `json:"audio_url" binding:"required"`
-		Format   string `json:"format,omitempty"`
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("用户未认证"))
+		return
 	}
+	uid := userID.(uint)
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+	var input ASRInput
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.BadRequest("音频文件上传失败: "+err.Error()))
+			return
+		}
+		if file.Size > maxASRUploadSize {
+			c.JSON(http.StatusBadRequest, response.BadRequest("音频文件不能超过20MB"))
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.BadRequest("读取音频文件失败"))
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.BadRequest("读取音频文件失败"))
+			return
+		}
+		input.Data = data
+		input.Format = c.PostForm("format")
+		if input.Format == "" {
+			input.Format = strings.TrimPrefix(filepath.Ext(file.Filename), ".")
+		}
+	} else {
+		var request struct {
+			AudioURL string `json:"audio_url" binding:"required"`
+			Format   string `json:"format,omitempty"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, response.BadRequest("无效的请求参数"))
+			return
+		}
+		if _, err := storage.OwnedKey(request.AudioURL); err != nil {
+			c.JSON(http.StatusBadRequest, response.BadRequest("只支持识别本站存储的音频"))
+			return
+		}
+		input.URL = request.AudioURL
+		input.Format = request.Format
+	}
+
+	// 时长只有识别后才能得知，因此先检查剩余额度，识别后再记录用量
+	limit := int64(config.LoadConfig().ASRDailySecondLimit)
+	used, err := GetDailyUsage(QuotaKindASR, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, response.InternalError("额度查询失败"))
+		return
+	}
+	if limit > 0 && used >= limit {
+		c.JSON(http.StatusTooManyRequests, response.TooManyRequests("今日语音识别额度已用完"))
 		return
 	}
 
-	text, err := RecognizeSpeech(request.AudioURL, request.Format)
+	result, err := RecognizeSpeechInput(c.Request.Context(), input)
 	if err != nil {
 		log.Printf("语音识别失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "语音识别失败: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, response.InternalError("语音识别失败: "+err.Error()))
 		return
 	}
+	AddQuotaUsage(QuotaKindASR, uid, int64(math.Ceil(result.Duration)))
 
-	c.JSON(http.StatusOK, gin.H{
-		"recognized_text": text,
-	})
+	data := gin.H{
+		"recognized_text": result.Text,
+		"language":        result.Language,
+		"duration":        result.Duration,
+		"words":           result.Words,
+	}
+	// 引擎未提供置信度时不返回该字段
+	if result.Confidence > 0 {
+		data["confidence"] = result.Confidence
+	}
+	c.JSON(http.StatusOK, response.Success(data))
 }
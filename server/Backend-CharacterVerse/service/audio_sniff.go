@@ -0,0 +1,32 @@
+package service
+
+import "bytes"
+
+// DetectAudioFormat 按文件头识别音频格式，返回扩展名，无法识别时返回空字符串
+func DetectAudioFormat(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return "mp3"
+	case len(data) >= 12 && bytes.Equal(data[:4], []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WAVE")):
+		return "wav"
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return "ogg"
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "webm"
+	case bytes.HasPrefix(data, []byte("#!AMR")):
+		return "amr"
+	case len(data) >= 12 && bytes.Equal(data[4:8], []byte("ftyp")):
+		// 只接受音频品牌，isom/mp42 等通用品牌多为视频
+		switch string(data[8:12]) {
+		case "M4A ", "M4B ", "M4P ":
+			return "m4a"
+		}
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		// 帧同步字：Layer 为 00 的是 AAC ADTS，其余为 MPEG 音频
+		if data[1]&0x06 == 0 {
+			return "aac"
+		}
+		return "mp3"
+	}
+	return ""
+}
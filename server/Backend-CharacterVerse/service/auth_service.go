@@ -0,0 +1,249 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/utils"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 吊销记录，保留到该会话签发的访问令牌全部过期
+const (
+	revokedSessionKeyPrefix = "auth:revoked:session:" // 已注销的会话
+	revokedUserKeyPrefix    = "auth:revoked:user:"    // 全部设备注销的时间（毫秒），此前签发的令牌失效
+	revokedLegacyKeyPrefix  = "auth:revoked:legacy:"  // 用户已注销旧版令牌
+)
+
+// 旧版令牌没有会话ID和签发时间，有效期固定为72小时
+const legacyTokenTTL = 72 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("刷新令牌无效或已过期")
+
+// TokenPair 登录或刷新后返回的令牌
+type TokenPair struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// 开始新的登录会话
+func startSession(userID uint) (*TokenPair, error) {
+	// 顺带清理该用户已过期的刷新令牌
+	database.DB.Where("user_id = ? AND expires_at < ?", userID, time.Now()).Delete(&model.RefreshToken{})
+
+	sessionID := utils.RandomID()
+	refreshToken, record, err := createRefreshToken(database.DB, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return issueAccessToken(userID, sessionID, refreshToken, record.ExpiresAt)
+}
+
+// RefreshSession 用刷新令牌换取新的访问令牌，旧的刷新令牌随即失效
+// 已轮换的令牌被再次使用说明可能已泄露，注销整个会话
+func RefreshSession(refreshToken string) (*TokenPair, error) {
+	var (
+		current     model.RefreshToken
+		replacement model.RefreshToken
+		newToken    string
+		reused      bool
+	)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashRefreshToken(refreshToken)).
+			First(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if current.RevokedAt != nil {
+			if current.ReplacedBy != 0 {
+				reused = true
+				return revokeSessionTokens(tx, current.UserID, current.SessionID)
+			}
+			return ErrInvalidRefreshToken
+		}
+		if time.Now().After(current.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		newToken, replacement, err = createRefreshToken(tx, current.UserID, current.SessionID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&current).Updates(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"replaced_by": replacement.ID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		log.Printf("刷新令牌被重复使用，注销会话: 用户ID=%d, 会话=%s", current.UserID, current.SessionID)
+		markSessionRevoked(current.SessionID)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return issueAccessToken(current.UserID, current.SessionID, newToken, replacement.ExpiresAt)
+}
+
+// Logout 注销当前会话：吊销其刷新令牌和已签发的访问令牌
+// 旧版令牌无法区分设备，注销时吊销该用户的全部旧版令牌
+func Logout(userID uint, sessionID string) error {
+	if sessionID == "" {
+		ctx := context.Background()
+		return database.RedisClient.Set(ctx, revokedLegacyKeyPrefix+strconv.FormatUint(uint64(userID), 10),
+			time.Now().UnixMilli(), legacyTokenTTL).Err()
+	}
+	if err := revokeSessionTokens(database.DB, userID, sessionID); err != nil {
+		return err
+	}
+	markSessionRevoked(sessionID)
+	return nil
+}
+
+// LogoutAllSessions 注销用户在所有设备上的登录
+func LogoutAllSessions(userID uint) error {
+	now := time.Now()
+	var sessionIDs []string
+	if err := database.DB.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Distinct().Pluck("session_id", &sessionIDs).Error; err != nil {
+		return err
+	}
+	if err := database.DB.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	for _, sessionID := range sessionIDs {
+		markSessionRevoked(sessionID)
+	}
+
+	// 兜底：按签发时间判断，覆盖没有会话ID的旧版令牌，需保留到旧版令牌全部过期
+	ctx := context.Background()
+	ttl := accessTokenTTL()
+	if ttl < legacyTokenTTL {
+		ttl = legacyTokenTTL
+	}
+	if err := database.RedisClient.Set(ctx, revokedUserKeyPrefix+strconv.FormatUint(uint64(userID), 10),
+		now.UnixMilli(), ttl).Err(); err != nil {
+		log.Printf("记录令牌吊销失败: 用户ID=%d, 错误=%v", userID, err)
+	}
+	return nil
+}
+
+// IsTokenRevoked 检查访问令牌是否已被注销
+// Redis 不可用时放行，访问令牌本身有效期较短
+func IsTokenRevoked(claims *utils.AccessClaims) bool {
+	ctx := context.Background()
+
+	userKey := strconv.FormatUint(uint64(claims.UserID), 10)
+
+	revokedKey := revokedLegacyKeyPrefix + userKey
+	if claims.SessionID != "" {
+		revokedKey = revokedSessionKeyPrefix + claims.SessionID
+	}
+	exists, err := database.RedisClient.Exists(ctx, revokedKey).Result()
+	if err != nil {
+		log.Printf("查询令牌吊销状态失败: %v", err)
+		return false
+	}
+	if exists > 0 {
+		return true
+	}
+
+	revokedAt, err := database.RedisClient.Get(ctx, revokedUserKeyPrefix+userKey).Int64()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("查询令牌吊销状态失败: %v", err)
+		}
+		return false
+	}
+	switch {
+	case claims.IssuedMs > 0:
+		return claims.IssuedMs <= revokedAt
+	case claims.IssuedAt != nil:
+		// 没有毫秒签发时间的令牌按秒比较，同一秒内签发的一并视为已注销
+		return claims.IssuedAt.Unix() <= revokedAt/1000
+	default:
+		// 旧版令牌没有签发时间，一并视为已注销
+		return true
+	}
+}
+
+func issueAccessToken(userID uint, sessionID, refreshToken string, refreshExpiresAt time.Time) (*TokenPair, error) {
+	token, expiresAt, err := utils.GenerateToken(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// 生成刷新令牌并保存其哈希
+func createRefreshToken(tx *gorm.DB, userID uint, sessionID string) (string, model.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", model.RefreshToken{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	record := model.RefreshToken{
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: hashRefreshToken(token),
+		ExpiresAt: time.Now().Add(time.Duration(config.LoadConfig().RefreshTokenTTLHours) * time.Hour),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", model.RefreshToken{}, err
+	}
+	return token, record, nil
+}
+
+// 吊销会话下所有未失效的刷新令牌
+func revokeSessionTokens(tx *gorm.DB, userID uint, sessionID string) error {
+	return tx.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND session_id = ? AND revoked_at IS NULL", userID, sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// 记录会话已注销，使其已签发的访问令牌立即失效
+func markSessionRevoked(sessionID string) {
+	ctx := context.Background()
+	if err := database.RedisClient.Set(ctx, revokedSessionKeyPrefix+sessionID, time.Now().Unix(), accessTokenTTL()).Err(); err != nil {
+		log.Printf("记录令牌吊销失败: 会话=%s, 错误=%v", sessionID, err)
+	}
+}
+
+func accessTokenTTL() time.Duration {
+	return time.Duration(config.LoadConfig().AccessTokenTTLMinutes) * time.Minute
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/utils"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 初始化认证测试环境并开始一个登录会话
+func setupAuthTest(t *testing.T) *TokenPair {
+	t.Helper()
+	t.Setenv("JWT_SECRET", "test-jwt-secret")
+	t.Setenv("ACCESS_TOKEN_TTL_MINUTES", "15")
+	t.Setenv("REFRESH_TOKEN_TTL_HOURS", "720")
+	setupTestDB(t, &model.RefreshToken{})
+	setupTestRedis(t)
+
+	pair, err := startSession(7)
+	if err != nil {
+		t.Fatalf("开始会话失败: %v", err)
+	}
+	return pair
+}
+
+func mustParseToken(t *testing.T, token string) *utils.AccessClaims {
+	t.Helper()
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		t.Fatalf("解析访问令牌失败: %v", err)
+	}
+	return claims
+}
+
+func TestRefreshSessionRotatesToken(t *testing.T) {
+	first := setupAuthTest(t)
+
+	second, err := RefreshSession(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("刷新后应轮换为新的刷新令牌")
+	}
+	if a, b := mustParseToken(t, first.Token), mustParseToken(t, second.Token); a.SessionID != b.SessionID {
+		t.Fatalf("刷新后会话ID应保持不变: %q != %q", a.SessionID, b.SessionID)
+	}
+
+	var old model.RefreshToken
+	database.DB.Where("token_hash = ?", hashRefreshToken(first.RefreshToken)).First(&old)
+	if old.RevokedAt == nil || old.ReplacedBy == 0 {
+		t.Fatalf("旧令牌应被标记为已轮换: %+v", old)
+	}
+
+	// 新令牌可以继续刷新
+	if _, err := RefreshSession(second.RefreshToken); err != nil {
+		t.Fatalf("使用新令牌刷新失败: %v", err)
+	}
+}
+
+func TestRefreshSessionReuseRevokesSession(t *testing.T) {
+	first := setupAuthTest(t)
+	second, err := RefreshSession(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("刷新失败: %v", err)
+	}
+
+	// 已轮换的令牌再次使用，视为泄露
+	if _, err := RefreshSession(first.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("重复使用旧令牌 err = %v, 期望 ErrInvalidRefreshToken", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"轮换出的新令牌随会话一并失效", second.RefreshToken},
+		{"旧令牌仍然无效", first.RefreshToken},
+		{"未知令牌", "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := RefreshSession(tt.token); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Fatalf("RefreshSession() err = %v, 期望 ErrInvalidRefreshToken", err)
+			}
+		})
+	}
+
+	var active int64
+	database.DB.Model(&model.RefreshToken{}).Where("revoked_at IS NULL").Count(&active)
+	if active != 0 {
+		t.Fatalf("会话下不应再有有效的刷新令牌, 剩余 %d 个", active)
+	}
+	// 已签发的访问令牌立即失效
+	if !IsTokenRevoked(mustParseToken(t, second.Token)) {
+		t.Fatal("会话注销后访问令牌应失效")
+	}
+}
+
+func TestRefreshSessionRejectsExpiredToken(t *testing.T) {
+	pair := setupAuthTest(t)
+	database.DB.Model(&model.RefreshToken{}).
+		Where("token_hash = ?", hashRefreshToken(pair.RefreshToken)).
+		Update("expires_at", time.Now().Add(-time.Minute))
+
+	if _, err := RefreshSession(pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("RefreshSession() err = %v, 期望 ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	pair := setupAuthTest(t)
+	claims := mustParseToken(t, pair.Token)
+	if IsTokenRevoked(claims) {
+		t.Fatal("新签发的令牌不应被视为已注销")
+	}
+
+	if err := LogoutAllSessions(claims.UserID); err != nil {
+		t.Fatal(err)
+	}
+	revokedAt, _ := database.RedisClient.Get(t.Context(), revokedUserKeyPrefix+strconv.FormatUint(uint64(claims.UserID), 10)).Int64()
+
+	tests := []struct {
+		name   string
+		claims *utils.AccessClaims
+		want   bool
+	}{
+		{"会话已注销", claims, true},
+		{"注销后签发的令牌", &utils.AccessClaims{UserID: claims.UserID, SessionID: "other", IssuedMs: revokedAt + 1}, false},
+		{"注销前签发的令牌", &utils.AccessClaims{UserID: claims.UserID, SessionID: "other", IssuedMs: revokedAt - 1}, true},
+		{"只有秒级签发时间且同一秒", &utils.AccessClaims{UserID: claims.UserID, SessionID: "other",
+			RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.UnixMilli(revokedAt))}}, true},
+		{"没有签发时间的旧版令牌", &utils.AccessClaims{UserID: claims.UserID}, true},
+		{"其他用户", &utils.AccessClaims{UserID: claims.UserID + 1, IssuedMs: revokedAt - 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTokenRevoked(tt.claims); got != tt.want {
+				t.Fatalf("IsTokenRevoked() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
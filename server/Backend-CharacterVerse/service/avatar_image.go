@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	_ "image/gif"
+	_ "image/png"
+)
+
+// 头像图片限制
+const (
+	MaxAvatarUploadSize = 5 << 20 // 上传文件大小上限
+	minAvatarDimension  = 128     // 最短边下限
+	maxAvatarDimension  = 4096    // 最长边上限，防止解码超大图片耗尽内存
+	avatarJPEGQuality   = 90
+)
+
+// 头像尺寸，第一个为主头像，其余为缩略图
+var avatarSizes = []int{512, 256, 128, 64}
+
+// 允许的图片格式（按内容嗅探，不信任扩展名）
+var allowedAvatarTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// decodeAvatarImage 嗅探并校验图片格式和尺寸后解码
+func decodeAvatarImage(data []byte) (image.Image, error) {
+	if len(data) == 0 {
+		return nil, errors.New("图片不能为空")
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedAvatarTypes[contentType] {
+		return nil, fmt.Errorf("不支持的图片格式: %s，仅支持 JPEG、PNG、GIF", contentType)
+	}
+
+	// 先读取尺寸，再决定是否完整解码
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("无法识别的图片: %w", err)
+	}
+	if cfg.Width < minAvatarDimension || cfg.Height < minAvatarDimension {
+		return nil, fmt.Errorf("图片尺寸不能小于%dx%d", minAvatarDimension, minAvatarDimension)
+	}
+	if cfg.Width > maxAvatarDimension || cfg.Height > maxAvatarDimension {
+		return nil, fmt.Errorf("图片尺寸不能大于%dx%d", maxAvatarDimension, maxAvatarDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("图片解码失败: %w", err)
+	}
+	return img, nil
+}
+
+// renderAvatarSizes 居中裁剪为正方形并缩放为各个尺寸的JPEG
+func renderAvatarSizes(img image.Image) (map[int][]byte, error) {
+	square := cropCenterSquare(img)
+
+	result := make(map[int][]byte, len(avatarSizes))
+	for _, size := range avatarSizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resizeSquare(square, size), &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+			return nil, fmt.Errorf("图片编码失败: %w", err)
+		}
+		result[size] = buf.Bytes()
+	}
+	return result, nil
+}
+
+// renderAvatarPreview 居中裁剪并缩放为最大尺寸的JPEG，用于候选图预览
+func renderAvatarPreview(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeSquare(cropCenterSquare(img), avatarSizes[0]), &jpeg.Options{Quality: avatarJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("图片编码失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// cropCenterSquare 居中裁剪为正方形，透明区域以白色填充
+func cropCenterSquare(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(square, square.Bounds(), img, offset, draw.Over)
+	return square
+}
+
+// resizeSquare 缩放正方形图片：缩小时按区域取平均，放大时双线性插值
+func resizeSquare(src *image.RGBA, size int) *image.RGBA {
+	srcSize := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if srcSize == size {
+		copy(dst.Pix, src.Pix)
+		return dst
+	}
+
+	scale := float64(srcSize) / float64(size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var c [4]float64
+			if scale > 1 {
+				c = areaAverage(src, float64(x)*scale, float64(y)*scale, scale)
+			} else {
+				c = bilinear(src, (float64(x)+0.5)*scale-0.5, (float64(y)+0.5)*scale-0.5)
+			}
+			i := dst.PixOffset(x, y)
+			for k := 0; k < 4; k++ {
+				dst.Pix[i+k] = uint8(c[k] + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// 计算源图中 [x0, x0+scale) × [y0, y0+scale) 区域的平均颜色
+func areaAverage(src *image.RGBA, x0, y0, scale float64) [4]float64 {
+	var sum [4]float64
+	var weight float64
+	maxIndex := src.Bounds().Dx() - 1
+
+	for sy := int(y0); float64(sy) < y0+scale && sy <= maxIndex; sy++ {
+		wy := overlap(float64(sy), y0, y0+scale)
+		for sx := int(x0); float64(sx) < x0+scale && sx <= maxIndex; sx++ {
+			w := wy * overlap(float64(sx), x0, x0+scale)
+			i := src.PixOffset(sx, sy)
+			for k := 0; k < 4; k++ {
+				sum[k] += float64(src.Pix[i+k]) * w
+			}
+			weight += w
+		}
+	}
+	if weight > 0 {
+		for k := range sum {
+			sum[k] /= weight
+		}
+	}
+	return sum
+}
+
+// 像素 [p, p+1) 与区间 [start, end) 的重叠长度
+func overlap(p, start, end float64) float64 {
+	lo, hi := p, p+1
+	if start > lo {
+		lo = start
+	}
+	if end < hi {
+		hi = end
+	}
+	if hi <= lo {
+		return 0
+	}
+	return hi - lo
+}
+
+// 双线性插值取色
+func bilinear(src *image.RGBA, fx, fy float64) [4]float64 {
+	maxIndex := src.Bounds().Dx() - 1
+	clamp := func(v int) int {
+		if v < 0 {
+			return 0
+		}
+		if v > maxIndex {
+			return maxIndex
+		}
+		return v
+	}
+
+	x0, y0 := int(fx), int(fy)
+	if fx < 0 {
+		x0 = -1
+	}
+	if fy < 0 {
+		y0 = -1
+	}
+	tx, ty := fx-float64(x0), fy-float64(y0)
+
+	var c [4]float64
+	for _, p := range []struct {
+		x, y int
+		w    float64
+	}{
+		{x0, y0, (1 - tx) * (1 - ty)},
+		{x0 + 1, y0, tx * (1 - ty)},
+		{x0, y0 + 1, (1 - tx) * ty},
+		{x0 + 1, y0 + 1, tx * ty},
+	} {
+		i := src.PixOffset(clamp(p.x), clamp(p.y))
+		for k := 0; k < 4; k++ {
+			c[k] += float64(src.Pix[i+k]) * p.w
+		}
+	}
+	return c
+}
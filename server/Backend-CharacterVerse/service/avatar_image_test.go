@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// 生成纯色图片
+func solidImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeAvatarImage(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	var gifBuf bytes.Buffer
+	if err := gif.Encode(&gifBuf, solidImage(200, 200, red), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"空数据", nil, true},
+		{"非图片内容", []byte("<html>not an image</html>"), true},
+		{"PNG", encodePNG(t, solidImage(200, 300, red)), false},
+		{"GIF", gifBuf.Bytes(), false},
+		{"尺寸过小", encodePNG(t, solidImage(minAvatarDimension-1, 200, red)), true},
+		{"尺寸过大", encodePNG(t, solidImage(maxAvatarDimension+1, minAvatarDimension, red)), true},
+		{"截断的PNG", encodePNG(t, solidImage(200, 200, red))[:100], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeAvatarImage(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeAvatarImage() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCropCenterSquare(t *testing.T) {
+	// 宽图左右两侧为红色，中间为蓝色，裁剪后只保留中间部分
+	red, blue := color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}
+	wide := solidImage(300, 100, red)
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			wide.Set(x, y, blue)
+		}
+	}
+	// 源图坐标不从原点开始时同样按中心裁剪
+	offset := wide.SubImage(image.Rect(50, 0, 250, 100))
+
+	tests := []struct {
+		name     string
+		img      image.Image
+		wantSide int
+		want     color.RGBA
+	}{
+		{"宽图取中间", wide, 100, blue},
+		{"非原点子图", offset, 100, blue},
+		{"透明区域填充白色", solidImage(120, 160, color.RGBA{}), 120, color.RGBA{R: 255, G: 255, B: 255, A: 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			square := cropCenterSquare(tt.img)
+			if side := square.Bounds().Dx(); side != tt.wantSide || square.Bounds().Dy() != tt.wantSide {
+				t.Fatalf("裁剪尺寸 = %v, 期望 %dx%d", square.Bounds(), tt.wantSide, tt.wantSide)
+			}
+			for _, p := range []image.Point{{0, 0}, {tt.wantSide - 1, tt.wantSide - 1}} {
+				if got := square.RGBAAt(p.X, p.Y); got != tt.want {
+					t.Fatalf("像素%v = %v, 期望 %v", p, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestResizeSquare(t *testing.T) {
+	gray := color.RGBA{R: 90, G: 120, B: 150, A: 255}
+
+	// 黑白棋盘格缩小一半后每个像素为灰色
+	checker := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				checker.Set(x, y, color.White)
+			} else {
+				checker.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		src  *image.RGBA
+		size int
+		want color.RGBA
+	}{
+		{"尺寸不变", solidImage(64, 64, gray), 64, gray},
+		{"缩小保持纯色", solidImage(300, 300, gray), 128, gray},
+		{"放大保持纯色", solidImage(100, 100, gray), 512, gray},
+		{"缩小按区域取平均", checker, 2, color.RGBA{R: 128, G: 128, B: 128, A: 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := resizeSquare(tt.src, tt.size)
+			if dst.Bounds() != image.Rect(0, 0, tt.size, tt.size) {
+				t.Fatalf("尺寸 = %v, 期望 %dx%d", dst.Bounds(), tt.size, tt.size)
+			}
+			for y := 0; y < tt.size; y++ {
+				for x := 0; x < tt.size; x++ {
+					if got := dst.RGBAAt(x, y); got != tt.want {
+						t.Fatalf("像素(%d,%d) = %v, 期望 %v", x, y, got, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRenderAvatarSizes(t *testing.T) {
+	img := solidImage(600, 400, color.RGBA{G: 200, A: 255})
+
+	sizes, err := renderAvatarSizes(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sizes) != len(avatarSizes) {
+		t.Fatalf("生成 %d 个尺寸, 期望 %d 个", len(sizes), len(avatarSizes))
+	}
+	for _, size := range avatarSizes {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(sizes[size]))
+		if err != nil {
+			t.Fatalf("尺寸%d不是有效的JPEG: %v", size, err)
+		}
+		if cfg.Width != size || cfg.Height != size {
+			t.Fatalf("尺寸%d实际为 %dx%d", size, cfg.Width, cfg.Height)
+		}
+	}
+
+	preview, err := renderAvatarPreview(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(preview))
+	if err != nil || cfg.Width != avatarSizes[0] || cfg.Height != avatarSizes[0] {
+		t.Fatalf("预览图 = %+v, err = %v, 期望 %dx%d", cfg, err, avatarSizes[0], avatarSizes[0])
+	}
+}
@@ -0,0 +1,418 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 头像任务调度参数
+const (
+	avatarPollInterval    = 5 * time.Second
+	avatarJobLease        = 30 * time.Minute // 执行超过该时间视为进程已退出，任务重新排队
+	avatarBackoffBase     = 30 * time.Second
+	avatarBackoffMax      = 30 * time.Minute
+	avatarLastErrorLimit  = 1000 // 失败原因最多保存的字符数
+	avatarGenerateTimeout = 5 * time.Minute
+)
+
+// 用户上传头像后取消未完成的生成任务
+var errAvatarJobCancelled = errors.New("已取消：用户上传了自定义头像")
+
+// 新任务入队时唤醒调度器，避免等待下一个轮询周期
+var avatarJobWakeup = make(chan struct{}, 1)
+
+// AvatarJobStatus 头像生成状态
+type AvatarJobStatus struct {
+	RoleID      uint          `json:"role_id"`
+	Status      string        `json:"status"`
+	AvatarURL   model.BlobRef `json:"avatar_url"`
+	Style       string        `json:"style,omitempty"`
+	Candidates  int           `json:"candidates,omitempty"` // 大于1时生成结果需在候选图中挑选
+	Attempts    int           `json:"attempts"`
+	MaxAttempts int           `json:"max_attempts"`
+	LastError   string        `json:"last_error,omitempty"` // 仅创建者可见
+	NextRunAt   *time.Time    `json:"next_run_at,omitempty"`
+	UpdatedAt   *time.Time    `json:"updated_at,omitempty"`
+}
+
+// EnqueueAvatarJob 为角色创建头像生成任务，count大于1时生成候选图供创建者挑选
+func EnqueueAvatarJob(role *model.Role, style string, count int) (*model.AvatarJob, error) {
+	if _, ok := getAvatarStyle(style); !ok {
+		return nil, fmt.Errorf("无效的头像风格: %s", style)
+	}
+	if count < 1 || count > maxImageCandidates {
+		return nil, fmt.Errorf("候选图片数量需在1-%d之间", maxImageCandidates)
+	}
+
+	prompt, negative := BuildAvatarPrompt(role, style)
+	job := model.AvatarJob{
+		RoleID:      role.ID,
+		Status:      model.AvatarJobPending,
+		Prompt:      prompt,
+		Negative:    negative,
+		Style:       style,
+		Candidates:  count,
+		MaxAttempts: config.LoadConfig().AvatarJobMaxAttempts,
+		NextRunAt:   time.Now(),
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = 1
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		return nil, err
+	}
+
+	select {
+	case avatarJobWakeup <- struct{}{}:
+	default:
+	}
+	return &job, nil
+}
+
+// 获取角色最近的头像任务，已取消的任务不计
+func getLatestAvatarJob(roleID uint) (*model.AvatarJob, error) {
+	var job model.AvatarJob
+	err := database.DB.Where("role_id = ? AND status <> ?", roleID, model.AvatarJobCancelled).Order("id DESC").First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RegenerateAvatar 手动重新生成头像（仅创建者）
+func RegenerateAvatar(roleID, userID uint, style string, count int) (*model.AvatarJob, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := getLatestAvatarJob(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.IsActive() {
+		return nil, errors.New("头像正在生成中，请稍后再试")
+	}
+
+	return EnqueueAvatarJob(role, style, count)
+}
+
+// GetAvatarStatus 获取角色头像生成状态
+func GetAvatarStatus(roleID, viewerID uint) (*AvatarJobStatus, error) {
+	role, err := GetAccessibleRole(roleID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &AvatarJobStatus{
+		RoleID:    role.ID,
+		Status:    getAvatarStatus(role),
+		AvatarURL: role.AvatarURL,
+	}
+
+	job, err := getLatestAvatarJob(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if job != nil {
+		status.Style = job.Style
+		status.Candidates = job.Candidates
+		status.Attempts = job.Attempts
+		status.MaxAttempts = job.MaxAttempts
+		status.UpdatedAt = &job.UpdatedAt
+		if job.Status == model.AvatarJobPending {
+			status.NextRunAt = &job.NextRunAt
+		}
+		if role.UserID == viewerID {
+			status.LastError = job.LastError
+		}
+	}
+	return status, nil
+}
+
+// StartAvatarWorkers 启动头像生成工作池
+func StartAvatarWorkers() {
+	workers := config.LoadConfig().AvatarWorkerCount
+	if workers <= 0 {
+		log.Println("头像生成任务已禁用")
+		return
+	}
+
+	jobs := make(chan uint)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for jobID := range jobs {
+				runAvatarJob(jobID)
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(avatarPollInterval)
+		defer ticker.Stop()
+
+		for {
+			requeueStaleAvatarJobs()
+			dispatchAvatarJobs(jobs, workers)
+
+			select {
+			case <-ticker.C:
+			case <-avatarJobWakeup:
+			}
+		}
+	}()
+	log.Printf("头像生成工作池已启动: 并发数=%d", workers)
+}
+
+// 执行中但超过租期的任务（进程重启或崩溃）重新排队，已用完尝试次数的直接标记为失败
+func requeueStaleAvatarJobs() {
+	staleBefore := time.Now().Add(-avatarJobLease)
+	result := database.DB.Model(&model.AvatarJob{}).
+		Where("status = ? AND started_at < ? AND attempts >= max_attempts", model.AvatarJobRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":      model.AvatarJobFailed,
+			"last_error":  "执行超时",
+			"finished_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("标记超时头像任务失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("已将%d个超时且达到最大尝试次数的头像任务标记为失败", result.RowsAffected)
+	}
+
+	result = database.DB.Model(&model.AvatarJob{}).
+		Where("status = ? AND started_at < ? AND attempts < max_attempts", model.AvatarJobRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":      model.AvatarJobPending,
+			"next_run_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("重置超时头像任务失败: %v", result.Error)
+	} else if result.RowsAffected > 0 {
+		log.Printf("已重新排队%d个超时头像任务", result.RowsAffected)
+	}
+}
+
+// 领取到期任务并分发给工作协程，阻塞直到全部分发
+func dispatchAvatarJobs(jobs chan<- uint, limit int) {
+	var due []model.AvatarJob
+	if err := database.DB.Select("id").
+		Where("status = ? AND next_run_at <= ?", model.AvatarJobPending, time.Now()).
+		Order("next_run_at").
+		Limit(limit).
+		Find(&due).Error; err != nil {
+		log.Printf("查询待处理头像任务失败: %v", err)
+		return
+	}
+
+	for _, job := range due {
+		// 以状态条件更新实现领取，多实例部署时只有一个实例能领取成功
+		now := time.Now()
+		result := database.DB.Model(&model.AvatarJob{}).
+			Where("id = ? AND status = ? AND attempts < max_attempts", job.ID, model.AvatarJobPending).
+			Updates(map[string]interface{}{
+				"status":     model.AvatarJobRunning,
+				"started_at": now,
+				"attempts":   gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		jobs <- job.ID
+	}
+}
+
+// 执行单个任务
+func runAvatarJob(jobID uint) {
+	var job model.AvatarJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		log.Printf("加载头像任务失败: 任务ID=%d, 错误=%v", jobID, err)
+		return
+	}
+
+	if err := processAvatarJob(&job); err != nil {
+		if errors.Is(err, errAvatarJobCancelled) {
+			log.Printf("头像任务已取消: 任务ID=%d, 角色ID=%d", job.ID, job.RoleID)
+			return
+		}
+		failAvatarJob(&job, err)
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Model(&job).Where("status = ?", model.AvatarJobRunning).Updates(map[string]interface{}{
+		"status":      model.AvatarJobSucceeded,
+		"last_error":  "",
+		"finished_at": now,
+	}).Error; err != nil {
+		log.Printf("更新头像任务状态失败: 任务ID=%d, 错误=%v", job.ID, err)
+	}
+}
+
+// 生成图片，单张时直接设为头像，多张时保存为候选图
+func processAvatarJob(job *model.AvatarJob) error {
+	role, err := database.GetRoleByID(job.RoleID)
+	if err != nil {
+		return errPermanent{err}
+	}
+
+	images, err := generateAvatarImages(job, role)
+	if err != nil {
+		return err
+	}
+
+	if job.Candidates <= 1 {
+		avatarURL, thumbnails, err := storeAvatarImage(context.Background(), role, images[0])
+		if err != nil {
+			return fmt.Errorf("图片处理失败: %w", err)
+		}
+		if err := checkAvatarJobRunning(job.ID); err != nil {
+			return err
+		}
+		if err := updateRoleAvatar(role.ID, avatarURL, thumbnails); err != nil {
+			return fmt.Errorf("更新头像URL失败: %w", err)
+		}
+		log.Printf("角色头像更新成功: 角色ID=%d, 地址=%s", role.ID, avatarURL)
+		return nil
+	}
+
+	candidates := make([]model.AvatarCandidate, 0, len(images))
+	for i, data := range images {
+		url, err := storeAvatarCandidate(role, job.ID, i, data)
+		if err != nil {
+			return fmt.Errorf("候选图处理失败: %w", err)
+		}
+		candidates = append(candidates, model.AvatarCandidate{JobID: job.ID, RoleID: role.ID, URL: url})
+	}
+	if err := checkAvatarJobRunning(job.ID); err != nil {
+		return err
+	}
+	if err := database.DB.Create(&candidates).Error; err != nil {
+		return fmt.Errorf("保存候选图失败: %w", err)
+	}
+	log.Printf("头像候选图生成成功: 角色ID=%d, 数量=%d", role.ID, len(candidates))
+	return nil
+}
+
+// 调用图片生成提供方并下载结果，上次已生成但后续失败时复用生成结果
+func generateAvatarImages(job *model.AvatarJob, role *model.Role) ([][]byte, error) {
+	if len(job.ImageURLs) > 0 {
+		images, err := downloadImages(job.ImageURLs)
+		if err == nil {
+			return images, nil
+		}
+		log.Printf("复用已生成图片失败，重新生成: %v", err)
+	}
+
+	generator, err := NewImageGenerator(config.LoadConfig())
+	if err != nil {
+		return nil, errPermanent{err}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), avatarGenerateTimeout)
+	defer cancel()
+	results, err := generator.Generate(ctx, ImageGenerateRequest{
+		Prompt:         job.Prompt,
+		NegativePrompt: job.Negative,
+		Count:          job.Candidates,
+		Title:          role.Name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("头像生成失败(%s): %w", generator.Name(), err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("头像生成失败(%s): 未返回图片", generator.Name())
+	}
+
+	var urls []string
+	images := make([][]byte, 0, len(results))
+	for _, result := range results {
+		if result.Data != nil {
+			images = append(images, result.Data)
+			continue
+		}
+		urls = append(urls, result.URL)
+	}
+	if len(urls) > 0 {
+		if err := database.DB.Model(job).Updates(model.AvatarJob{ImageURLs: urls}).Error; err != nil {
+			return nil, fmt.Errorf("保存生成结果失败: %w", err)
+		}
+		downloaded, err := downloadImages(urls)
+		if err != nil {
+			return nil, fmt.Errorf("图片下载失败: %w", err)
+		}
+		images = append(images, downloaded...)
+	}
+	return images, nil
+}
+
+func downloadImages(urls []string) ([][]byte, error) {
+	images := make([][]byte, 0, len(urls))
+	for _, url := range urls {
+		data, err := downloadImage(url)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// 执行期间任务可能已被取消（用户上传了头像）
+func checkAvatarJobRunning(jobID uint) error {
+	var current model.AvatarJob
+	if err := database.DB.Select("status").First(&current, jobID).Error; err != nil {
+		return err
+	}
+	if current.Status != model.AvatarJobRunning {
+		return errPermanent{errAvatarJobCancelled}
+	}
+	return nil
+}
+
+// errPermanent 不可重试的错误
+type errPermanent struct{ err error }
+
+func (e errPermanent) Error() string { return e.err.Error() }
+
+func (e errPermanent) Unwrap() error { return e.err }
+
+// 记录失败，未达到最大次数时按指数退避重新排队
+func failAvatarJob(job *model.AvatarJob, cause error) {
+	message := cause.Error()
+	if runes := []rune(message); len(runes) > avatarLastErrorLimit {
+		message = string(runes[:avatarLastErrorLimit])
+	}
+
+	updates := map[string]interface{}{"last_error": message}
+	var permanent errPermanent
+	if errors.As(cause, &permanent) || job.Attempts >= job.MaxAttempts {
+		updates["status"] = model.AvatarJobFailed
+		updates["finished_at"] = time.Now()
+		log.Printf("头像任务最终失败: 任务ID=%d, 角色ID=%d, 尝试次数=%d, 错误=%v", job.ID, job.RoleID, job.Attempts, cause)
+	} else {
+		backoff := avatarBackoffBase << (job.Attempts - 1)
+		if backoff > avatarBackoffMax || backoff <= 0 {
+			backoff = avatarBackoffMax
+		}
+		updates["status"] = model.AvatarJobPending
+		updates["next_run_at"] = time.Now().Add(backoff)
+		log.Printf("头像任务失败，%v后重试: 任务ID=%d, 尝试次数=%d/%d, 错误=%v", backoff, job.ID, job.Attempts, job.MaxAttempts, cause)
+	}
+
+	// 执行期间任务可能已被取消，不覆盖取消状态
+	if err := database.DB.Model(job).Where("status = ?", model.AvatarJobRunning).Updates(updates).Error; err != nil {
+		log.Printf("更新头像任务状态失败: 任务ID=%d, 错误=%v", job.ID, err)
+	}
+}
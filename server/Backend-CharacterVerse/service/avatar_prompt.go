@@ -0,0 +1,123 @@
+package service
+
+import (
+	"Backend-CharacterVerse/model"
+	"fmt"
+	"strings"
+)
+
+// 提示词中人设描述的最大字符数（百炼提示词上限为800字符）
+const avatarPersonaMaxLength = 150
+
+// AvatarStyle 头像风格预设
+type AvatarStyle struct {
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	Prompt         string `json:"-"`
+	NegativePrompt string `json:"-"`
+}
+
+// 头像风格
+const (
+	AvatarStyleAnime     = "anime"
+	AvatarStyleRealistic = "realistic"
+	AvatarStyleInk       = "ink"
+)
+
+// 通用的反向提示词
+const avatarBaseNegativePrompt = "模糊，低质量，畸形，多人，文字，水印"
+
+var avatarStyles = []AvatarStyle{
+	{
+		Key:            AvatarStyleAnime,
+		Name:           "动漫",
+		Prompt:         "日系动漫风格，精致线条，明亮色彩，赛璐璐上色",
+		NegativePrompt: "写实照片，3D渲染",
+	},
+	{
+		Key:            AvatarStyleRealistic,
+		Name:           "写实",
+		Prompt:         "写实摄影风格，真实质感，柔和自然光，浅景深，高清细节",
+		NegativePrompt: "卡通，动漫，绘画",
+	},
+	{
+		Key:            AvatarStyleInk,
+		Name:           "水墨",
+		Prompt:         "中国传统水墨画风格，写意笔触，大量留白，淡雅墨色，宣纸质感",
+		NegativePrompt: "照片，3D渲染，鲜艳色彩",
+	},
+}
+
+// GetAvatarStyles 获取全部头像风格预设
+func GetAvatarStyles() []AvatarStyle {
+	return avatarStyles
+}
+
+// 查找风格预设，空字符串表示不指定风格
+func getAvatarStyle(key string) (AvatarStyle, bool) {
+	if key == "" {
+		return AvatarStyle{}, true
+	}
+	for _, style := range avatarStyles {
+		if style.Key == key {
+			return style, true
+		}
+	}
+	return AvatarStyle{}, false
+}
+
+// 年龄段描述
+func describeAge(age int, gender string) string {
+	switch {
+	case age <= 0:
+		return ""
+	case age < 13:
+		return "儿童"
+	case age < 18:
+		if gender == "女" {
+			return "少女"
+		}
+		return "少年"
+	case age < 35:
+		return "青年"
+	case age < 55:
+		return "中年"
+	default:
+		return "老年"
+	}
+}
+
+// BuildAvatarPrompt 根据角色设定和风格生成正向、反向提示词
+func BuildAvatarPrompt(role *model.Role, styleKey string) (string, string) {
+	style, _ := getAvatarStyle(styleKey)
+
+	parts := []string{"单人半身肖像", "面向镜头"}
+	switch role.Gender {
+	case "男":
+		parts = append(parts, "男性")
+	case "女":
+		parts = append(parts, "女性")
+	}
+	if ageGroup := describeAge(role.Age, role.Gender); ageGroup != "" {
+		parts = append(parts, fmt.Sprintf("%s（约%d岁）", ageGroup, role.Age))
+	}
+	if role.Tag != "" {
+		parts = append(parts, "题材："+role.Tag)
+	}
+
+	persona := []rune(strings.TrimSpace(role.Description))
+	if len(persona) > avatarPersonaMaxLength {
+		persona = persona[:avatarPersonaMaxLength]
+	}
+
+	prompt := fmt.Sprintf("角色头像：%s。%s。人物设定：%s", role.Name, strings.Join(parts, "，"), string(persona))
+	if style.Prompt != "" {
+		prompt += "。画面风格：" + style.Prompt
+	}
+
+	negative := avatarBaseNegativePrompt
+	if style.NegativePrompt != "" {
+		negative = style.NegativePrompt + "，" + negative
+	}
+	return prompt, negative
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 将图片裁剪缩放为各尺寸并保存，返回主头像和全部尺寸的对象键
+func storeAvatarImage(ctx context.Context, role *model.Role, data []byte) (model.BlobRef, model.BlobRefMap, error) {
+	img, err := decodeAvatarImage(data)
+	if err != nil {
+		return "", nil, err
+	}
+	rendered, err := renderAvatarSizes(img)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 同一张头像的各尺寸共用对象键前缀
+	base := storage.NewKey(fmt.Sprintf("avatars/%d", role.ID), "")
+	keys := make(model.BlobRefMap, len(avatarSizes))
+	for _, size := range avatarSizes {
+		key, err := uploadImage(ctx, model.ObjectKindAvatar, role.UserID, fmt.Sprintf("%s_%d.jpg", base, size), rendered[size])
+		if err != nil {
+			return "", nil, fmt.Errorf("上传%dpx头像失败: %w", size, err)
+		}
+		keys[strconv.Itoa(size)] = model.BlobRef(key)
+	}
+	return keys[strconv.Itoa(avatarSizes[0])], keys, nil
+}
+
+// 裁剪候选图并保存，返回预览图对象键
+func storeAvatarCandidate(role *model.Role, jobID uint, index int, data []byte) (model.BlobRef, error) {
+	img, err := decodeAvatarImage(data)
+	if err != nil {
+		return "", err
+	}
+	preview, err := renderAvatarPreview(img)
+	if err != nil {
+		return "", err
+	}
+	key, err := uploadImage(context.Background(), model.ObjectKindAvatarCandidate, role.UserID, fmt.Sprintf("avatars/%d/candidates/%d_%d.jpg", role.ID, jobID, index), preview)
+	return model.BlobRef(key), err
+}
+
+// 更新角色头像及缩略图
+func updateRoleAvatar(roleID uint, avatarURL model.BlobRef, thumbnails model.BlobRefMap) error {
+	return database.DB.Model(&model.Role{}).
+		Where("id = ?", roleID).
+		Updates(model.Role{AvatarURL: avatarURL, AvatarThumbnails: thumbnails}).Error
+}
+
+// UploadRoleAvatar 使用用户上传的图片作为角色头像（仅创建者），返回主头像和缩略图URL
+func UploadRoleAvatar(roleID, userID uint, data []byte) (model.BlobRef, model.BlobRefMap, error) {
+	if len(data) > MaxAvatarUploadSize {
+		return "", nil, fmt.Errorf("图片不能超过%dMB", MaxAvatarUploadSize>>20)
+	}
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 用户上传的图片计入其存储配额
+	avatarURL, thumbnails, err := storeAvatarImage(storage.WithUploader(context.Background(), userID), role, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// 取消尚未完成的生成任务，避免覆盖用户上传的头像
+	if err := cancelAvatarJobs(role.ID); err != nil {
+		return "", nil, err
+	}
+
+	if err := updateRoleAvatar(role.ID, avatarURL, thumbnails); err != nil {
+		return "", nil, errors.New("更新头像失败")
+	}
+	return avatarURL, thumbnails, nil
+}
+
+// 取消角色尚未完成的生成任务
+func cancelAvatarJobs(roleID uint) error {
+	now := time.Now()
+	return database.DB.Model(&model.AvatarJob{}).
+		Where("role_id = ? AND status IN ?", roleID, []string{model.AvatarJobPending, model.AvatarJobRunning}).
+		Updates(map[string]interface{}{
+			"status":      model.AvatarJobCancelled,
+			"last_error":  errAvatarJobCancelled.Error(),
+			"finished_at": now,
+		}).Error
+}
+
+// GetAvatarCandidates 获取最近一次生成的头像候选图（仅创建者）
+func GetAvatarCandidates(roleID, userID uint) ([]model.AvatarCandidate, error) {
+	if _, err := getOwnedRole(database.DB, roleID, userID); err != nil {
+		return nil, err
+	}
+
+	candidates := []model.AvatarCandidate{}
+	var latest model.AvatarCandidate
+	err := database.DB.Where("role_id = ?", roleID).Order("id DESC").First(&latest).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return candidates, nil
+		}
+		return nil, err
+	}
+
+	if err := database.DB.Where("job_id = ?", latest.JobID).Order("id").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// SelectAvatarCandidate 将候选图设为角色头像（仅创建者）
+func SelectAvatarCandidate(roleID, userID, candidateID uint) (model.BlobRef, model.BlobRefMap, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var candidate model.AvatarCandidate
+	if err := database.DB.Where("id = ? AND role_id = ?", candidateID, role.ID).First(&candidate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, errors.New("候选图不存在")
+		}
+		return "", nil, err
+	}
+
+	data, err := storage.Get(context.Background(), string(candidate.URL))
+	if err != nil {
+		return "", nil, fmt.Errorf("读取候选图失败: %w", err)
+	}
+	avatarURL, thumbnails, err := storeAvatarImage(context.Background(), role, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err := cancelAvatarJobs(role.ID); err != nil {
+		return "", nil, err
+	}
+	if err := updateRoleAvatar(role.ID, avatarURL, thumbnails); err != nil {
+		return "", nil, errors.New("更新头像失败")
+	}
+	return avatarURL, thumbnails, nil
+}
@@ -3,14 +3,16 @@ package service
 import (
 	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"strings"
@@ -25,8 +27,21 @@ import (
 const (
 	MessageTypeText  = "text"
 	MessageTypeVoice = "voice"
+
+	MessageTypeVoiceStored = "voice_stored" // 服务端已保存客户端直接发送的语音，返回访问地址
 )
 
+// 通过WebSocket直接发送的语音消息允许的格式
+var allowedChatAudioFormats = map[string]bool{
+	"mp3": true, "wav": true, "m4a": true, "ogg": true, "webm": true, "aac": true, "amr": true,
+}
+
+// 单帧大小上限：音频上限按base64编码膨胀后再预留JSON字段的空间
+const maxChatFrameSize = maxASRUploadSize/3*4 + 64<<10
+
+// 已上传TTS音频URL的复用时间
+const ttsURLCacheDuration = 7 * 24 * time.Hour
+
 // 定义回复类型常量
 const (
 	ResponseTypeText   = 0 // 文字回复
@@ -40,14 +55,19 @@ type ChatMessage struct {
 	Message      string `json:"message"`
 	Type         string `json:"type"`             // text 或 voice
 	Format       string `json:"format,omitempty"` // 语音格式，如 mp3, wav
+	Audio        string `json:"audio,omitempty"`  // base64编码的语音数据，message为空时使用
 	ResponseType int    `json:"response_type"`    // 回复类型: 0=文字, 1=语音, 2=随机
+
+	VoiceParams *model.VoiceParams `json:"voice_params,omitempty"` // 本条消息的语音参数覆盖
 }
 
 type ChatResponse struct {
 	RoleID  uint   `json:"role_id"`
-	Message string `json:"message"`          // 文本内容或base64编码的语音
+	Message string `json:"message"`          // 文本内容或语音URL
 	Type    string `json:"type"`             // text 或 voice
 	Format  string `json:"format,omitempty"` // 语音格式
+
+	Emotions []EmotionSegment `json:"emotions,omitempty"` // 按句的情感标记，供前端驱动头像动画
 }
 
 // 七牛云API请求/响应结构
@@ -79,8 +99,13 @@ func HandleChatSession(conn *websocket.Conn, userID uint) {
 		}
 	}()
 
+	conn.SetReadLimit(maxChatFrameSize)
+
+	// 未携带语音数据的语音消息，等待下一帧二进制音频
+	var pendingVoice *ChatMessage
+
 	for {
-		_, msgBytes, err := conn.ReadMessage()
+		frameType, msgBytes, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket连接异常关闭: %v", err)
@@ -88,18 +113,57 @@ func HandleChatSession(conn *websocket.Conn, userID uint) {
 			break
 		}
 
+		// 二进制帧为上一条语音消息的音频数据
+		if frameType == websocket.BinaryMessage {
+			if pendingVoice == nil {
+				sendError(conn, "发送音频数据前需先发送语音消息")
+				continue
+			}
+			chatMsg := *pendingVoice
+			pendingVoice = nil
+			handleVoiceUpload(conn, userID, chatMsg, msgBytes)
+			continue
+		}
+		pendingVoice = nil
+
 		var chatMsg ChatMessage
 		if err := json.Unmarshal(msgBytes, &chatMsg); err != nil {
 			sendError(conn, "消息格式错误: "+err.Error())
 			continue
 		}
 
+		// 校验语音参数覆盖
+		if chatMsg.VoiceParams != nil {
+			if err := chatMsg.VoiceParams.Validate(); err != nil {
+				sendError(conn, "语音参数错误: "+err.Error())
+				continue
+			}
+		}
+
+		// 私有角色仅创建者可以对话
+		if _, err := GetAccessibleRole(chatMsg.RoleID, userID); err != nil {
+			sendError(conn, "获取角色信息失败: "+err.Error())
+			continue
+		}
+
 		// 处理不同类型的消息
 		switch chatMsg.Type {
 		case MessageTypeText:
 			handleTextMessage(conn, userID, chatMsg)
 		case MessageTypeVoice:
-			handleVoiceMessage(conn, userID, chatMsg)
+			switch {
+			case chatMsg.Audio != "":
+				audioData, err := base64.StdEncoding.DecodeString(chatMsg.Audio)
+				if err != nil {
+					sendError(conn, "语音数据格式错误: "+err.Error())
+					continue
+				}
+				handleVoiceUpload(conn, userID, chatMsg, audioData)
+			case chatMsg.Message == "":
+				pendingVoice = &chatMsg
+			default:
+				handleVoiceMessage(conn, userID, chatMsg)
+			}
 		default:
 			sendError(conn, "不支持的消息类型: "+chatMsg.Type)
 		}
@@ -123,25 +187,75 @@ func handleTextMessage(conn *websocket.Conn, userID uint, chatMsg ChatMessage) {
 	clearUserCache(userID)
 
 	// 处理消息并获取AI回复
-	response, err := processMessage(userID, chatMsg.RoleID, chatMsg.Message, chatMsg.Type, "")
+	response, roleRevision, err := processMessage(userID, chatMsg.RoleID, chatMsg.Message, chatMsg.Type, "")
 	if err != nil {
 		sendError(conn, "处理消息失败: "+err.Error())
 		return
 	}
 
 	// 根据用户期望的回复类型发送响应
-	sendResponseBasedOnType(conn, userID, chatMsg, response) // 修复：传入userID
+	sendResponseBasedOnType(conn, userID, chatMsg, response, roleRevision) // 修复：传入userID
 }
 
 // 处理语音消息
 func handleVoiceMessage(conn *websocket.Conn, userID uint, chatMsg ChatMessage) {
 	// 1. 语音识别
-	text, err := RecognizeSpeech(chatMsg.Message, chatMsg.Format)
+	asrResult, err := RecognizeSpeech(chatMsg.Message, chatMsg.Format)
 	if err != nil {
 		sendError(conn, "语音识别失败: "+err.Error())
 		return
 	}
 
+	replyToVoiceMessage(conn, userID, chatMsg, asrResult.Text, chatMsg.Message)
+}
+
+// 处理直接发送的语音数据：保存到对象存储后识别
+func handleVoiceUpload(conn *websocket.Conn, userID uint, chatMsg ChatMessage, audioData []byte) {
+	format := strings.ToLower(chatMsg.Format)
+	if !allowedChatAudioFormats[format] {
+		sendError(conn, "不支持的语音格式: "+chatMsg.Format)
+		return
+	}
+	if len(audioData) == 0 {
+		sendError(conn, "语音数据为空")
+		return
+	}
+	if len(audioData) > maxASRUploadSize {
+		sendError(conn, "语音数据不能超过20MB")
+		return
+	}
+
+	// 用户发送的语音计入其存储配额
+	voiceKey, err := uploadAudio(storage.WithUploader(context.Background(), userID), model.ObjectKindVoice, userID, audioData, format)
+	if err != nil {
+		sendError(conn, "语音保存失败: "+err.Error())
+		return
+	}
+	voiceURL := storage.ResolveURL(voiceKey)
+
+	// 返回已保存语音的访问地址，供前端回放
+	if err := conn.WriteJSON(ChatResponse{
+		RoleID:  chatMsg.RoleID,
+		Message: voiceURL,
+		Type:    MessageTypeVoiceStored,
+		Format:  format,
+	}); err != nil {
+		log.Printf("发送消息错误: %v", err)
+	}
+
+	// 同时提供URL和原始数据，由识别引擎选择使用
+	asrResult, err := RecognizeSpeechInput(context.Background(), ASRInput{URL: voiceURL, Data: audioData, Format: format})
+	if err != nil {
+		sendError(conn, "语音识别失败: "+err.Error())
+		return
+	}
+
+	chatMsg.Format = format
+	replyToVoiceMessage(conn, userID, chatMsg, asrResult.Text, voiceKey)
+}
+
+// 保存识别后的用户语音消息并回复
+func replyToVoiceMessage(conn *websocket.Conn, userID uint, chatMsg ChatMessage, text, voiceRef string) {
 	log.Printf("语音识别结果 (用户ID: %d, 角色ID: %d): %s", userID, chatMsg.RoleID, text)
 
 	// 2. 保存用户语音消息到数据库
@@ -150,7 +264,7 @@ func handleVoiceMessage(conn *websocket.Conn, userID uint, chatMsg ChatMessage)
 		chatMsg.RoleID,
 		text, // 保存语音转文字后的文本
 		chatMsg.Type,
-		chatMsg.Message, // 保存语音URL
+		voiceRef, // 保存语音对象键或URL
 	); err != nil {
 		log.Printf("保存用户语音消息失败: %v", err)
 	}
@@ -159,31 +273,36 @@ func handleVoiceMessage(conn *websocket.Conn, userID uint, chatMsg ChatMessage)
 	clearUserCache(userID)
 
 	// 3. 处理文本消息
-	response, err := processMessage(userID, chatMsg.RoleID, text, chatMsg.Type, chatMsg.Message)
+	response, roleRevision, err := processMessage(userID, chatMsg.RoleID, text, chatMsg.Type, voiceRef)
 	if err != nil {
 		sendError(conn, "处理消息失败: "+err.Error())
 		return
 	}
 
 	// 4. 根据用户期望的回复类型发送响应
-	sendResponseBasedOnType(conn, userID, chatMsg, response) // 修复：传入userID
+	sendResponseBasedOnType(conn, userID, chatMsg, response, roleRevision) // 修复：传入userID
 }
 
 // 根据回复类型发送响应
-func sendResponseBasedOnType(conn *websocket.Conn, userID uint, chatMsg ChatMessage, responseText string) {
+func sendResponseBasedOnType(conn *websocket.Conn, userID uint, chatMsg ChatMessage, rawResponse string, roleRevision int) {
+	// 解析情感标记，发送和保存的都是去除标记后的文本
+	emotions := ParseEmotionSegments(rawResponse, "")
+	responseText := JoinEmotionSegments(emotions)
+
 	// 确定最终回复类型
 	responseType := determineResponseType(chatMsg.ResponseType)
 
 	// 根据回复类型处理
 	switch responseType {
 	case ResponseTypeVoice:
-		sendVoiceResponse(conn, userID, chatMsg, responseText) // 修复：传入userID
+		sendVoiceResponse(conn, userID, chatMsg, responseText, emotions, roleRevision) // 修复：传入userID
 	default:
 		// 默认发送文本回复
 		if err := conn.WriteJSON(ChatResponse{
-			RoleID:  chatMsg.RoleID,
-			Message: responseText,
-			Type:    MessageTypeText,
+			RoleID:   chatMsg.RoleID,
+			Message:  responseText,
+			Type:     MessageTypeText,
+			Emotions: emotions,
 		}); err != nil {
 			log.Printf("发送消息错误: %v", err)
 		}
@@ -193,6 +312,7 @@ func sendResponseBasedOnType(conn *websocket.Conn, userID uint, chatMsg ChatMess
 			userID, // 修复：使用传入的userID
 			chatMsg.RoleID,
 			responseText,
+			roleRevision,
 		); err != nil {
 			log.Printf("保存AI文本消息失败: %v", err)
 		}
@@ -215,69 +335,41 @@ func determineResponseType(requestedType int) int {
 	return requestedType
 }
 
-// 上传语音文件到服务器
-func uploadVoiceToServer(audioData []byte) (string, error) {
-	// 创建表单数据
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// 添加文件字段
-	part, err := writer.CreateFormFile("file", "tts_audio.mp3")
-	if err != nil {
-		return "", fmt.Errorf("创建表单文件失败: %w", err)
-	}
-
-	if _, err := part.Write(audioData); err != nil {
-		return "", fmt.Errorf("写入音频数据失败: %w", err)
+// 保存音频到对象存储，返回对象键
+func uploadAudio(ctx context.Context, kind string, ownerID uint, audioData []byte, ext string) (string, error) {
+	contentType := http.DetectContentType(audioData)
+	if ext == "mp3" {
+		contentType = "audio/mpeg"
 	}
-
-	// 关闭writer
-	if err := writer.Close(); err != nil {
-		return "", fmt.Errorf("关闭表单写入器失败: %w", err)
-	}
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", "https://ai.mcell.top/api/upload_voice", body)
+	key, err := storeObject(ctx, kind, ownerID, storage.NewKey(kind, ext), audioData, contentType)
 	if err != nil {
-		return "", fmt.Errorf("创建上传请求失败: %w", err)
-	}
-
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// 发送请求
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("上传请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("上传失败，状态码: %d", resp.StatusCode)
-	}
-
-	// 解析响应
-	var response struct {
-		Message  string `json:"message"`
-		Filename string `json:"filename"`
-		URL      string `json:"url"`
+		return "", fmt.Errorf("保存音频失败: %w", err)
 	}
+	return key, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("解析上传响应失败: %w", err)
+// 保存TTS音频，按缓存键复用已保存文件的对象键
+func uploadTTSAudio(userID uint, cacheKey string, audioData []byte) (string, error) {
+	ctx := context.Background()
+	objectKey := "tts:url:" + cacheKey
+	if key, err := database.RedisClient.Get(ctx, objectKey).Result(); err == nil && key != "" {
+		// 缓存的文件可能已被回收；复用时清除无引用标记，避免刚返回的文件被回收
+		if key = storage.NormalizeRef(key); reuseStoredObject(key) {
+			return key, nil
+		}
 	}
 
-	if response.Message != "文件上传成功" {
-		return "", errors.New("上传失败: " + response.Message)
+	key, err := uploadAudio(ctx, model.ObjectKindTTS, userID, audioData, "mp3")
+	if err != nil {
+		return "", err
 	}
 
-	// 返回完整的URL
-	return "https://ai.mcell.top" + response.URL, nil
+	database.RedisClient.Set(ctx, objectKey, key, ttsURLCacheDuration)
+	return key, nil
 }
 
 // 发送语音回复
-func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, responseText string) {
+func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, responseText string, emotions []EmotionSegment, roleRevision int) {
 	// 获取角色信息以确定音色
 	role, err := database.GetRoleByID(chatMsg.RoleID)
 	if err != nil {
@@ -295,15 +387,22 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 		role.VoiceType = "qiniu_zh_female_wwxkjx" // 设置默认音色
 	}
 
+	// 克隆声音需转换为提供方的声音标识
+	ttsVoice := ResolveTTSVoice(role.VoiceType)
+
+	// 角色语音参数叠加回复的主要情感，再叠加消息级覆盖
+	voiceParams := ApplyEmotion(role.GetVoiceParams(), DominantEmotion(emotions)).Merge(chatMsg.VoiceParams)
+
 	// 语音合成 (TTS)
-	audioData, err := GenerateQiniuTTS(responseText, role.VoiceType, "mp3", 1.0)
+	audioData, err := GenerateTTS(ttsVoice, responseText, "mp3", voiceParams)
 	if err != nil {
 		log.Printf("语音合成失败: %v", err)
 		// 如果TTS失败，回退到文本回复
 		if err := conn.WriteJSON(ChatResponse{
-			RoleID:  chatMsg.RoleID,
-			Message: responseText,
-			Type:    MessageTypeText,
+			RoleID:   chatMsg.RoleID,
+			Message:  responseText,
+			Type:     MessageTypeText,
+			Emotions: emotions,
 		}); err != nil {
 			log.Printf("发送消息错误: %v", err)
 		}
@@ -313,6 +412,7 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 			userID, // 修复：使用传入的userID
 			chatMsg.RoleID,
 			responseText,
+			roleRevision,
 		); err != nil {
 			log.Printf("保存AI文本消息失败: %v", err)
 		}
@@ -320,15 +420,16 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 		return
 	}
 
-	// 上传语音文件并获取URL
-	voiceURL, err := uploadVoiceToServer(audioData)
+	// 保存语音文件（相同内容复用已保存的文件）
+	voiceKey, err := uploadTTSAudio(userID, TTSCacheKey(ttsVoice.CacheID(), voiceParams, "mp3", responseText), audioData)
 	if err != nil {
 		log.Printf("语音上传失败: %v", err)
 		// 如果上传失败，回退到文本回复
 		if err := conn.WriteJSON(ChatResponse{
-			RoleID:  chatMsg.RoleID,
-			Message: responseText,
-			Type:    MessageTypeText,
+			RoleID:   chatMsg.RoleID,
+			Message:  responseText,
+			Type:     MessageTypeText,
+			Emotions: emotions,
 		}); err != nil {
 			log.Printf("发送消息错误: %v", err)
 		}
@@ -338,6 +439,7 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 			userID, // 修复：使用传入的userID
 			chatMsg.RoleID,
 			responseText,
+			roleRevision,
 		); err != nil {
 			log.Printf("保存AI文本消息失败: %v", err)
 		}
@@ -350,7 +452,8 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 		userID, // 修复：使用传入的userID
 		chatMsg.RoleID,
 		responseText,
-		voiceURL,
+		voiceKey,
+		roleRevision,
 	); err != nil {
 		log.Printf("保存AI语音消息失败: %v", err)
 	}
@@ -358,20 +461,22 @@ func sendVoiceResponse(conn *websocket.Conn, userID uint, chatMsg ChatMessage, r
 
 	// 发送语音回复给前端（返回语音URL而不是base64数据）
 	if err := conn.WriteJSON(ChatResponse{
-		RoleID:  chatMsg.RoleID,
-		Message: voiceURL, // 返回语音URL
-		Type:    MessageTypeVoice,
-		Format:  "mp3",
+		RoleID:   chatMsg.RoleID,
+		Message:  storage.ResolveURL(voiceKey), // 返回语音URL
+		Type:     MessageTypeVoice,
+		Format:   "mp3",
+		Emotions: emotions,
 	}); err != nil {
 		log.Printf("发送语音消息错误: %v", err)
 	}
 }
 
 // 处理消息的核心逻辑
-func processMessage(userID, roleID uint, message string, messageType string, voiceURL string) (string, error) {
-	role, err := database.GetRoleByID(roleID)
+// 返回AI回复及生成回复时使用的角色版本号
+func processMessage(userID, roleID uint, message string, messageType string, voiceURL string) (string, int, error) {
+	role, err := GetAccessibleRole(roleID, userID)
 	if err != nil {
-		return "", fmt.Errorf("获取角色信息失败: %w", err)
+		return "", 0, fmt.Errorf("获取角色信息失败: %w", err)
 	}
 
 	// 获取已有的摘要
@@ -383,17 +488,17 @@ func processMessage(userID, roleID uint, message string, messageType string, voi
 	// 获取最近的聊天记录
 	history, err := database.GetChatHistory(userID, roleID, 5)
 	if err != nil {
-		return "", fmt.Errorf("获取历史消息失败: %w", err)
+		return "", 0, fmt.Errorf("获取历史消息失败: %w", err)
 	}
 
 	// 第一步：获取聊天回复
 	chatMessages := buildChatMessages(role, history, message, existingSummary)
 	userResponse, err := callQiniuLLM(chatMessages)
 	if err != nil {
-		return "", fmt.Errorf("调用大模型获取回复失败: %w", err)
+		return "", 0, fmt.Errorf("调用大模型获取回复失败: %w", err)
 	}
 
-	return userResponse, nil
+	return userResponse, role.Revision, nil
 }
 
 // 构建聊天请求的消息（使用完整的角色信息）
@@ -403,7 +508,7 @@ func buildChatMessages(role *model.Role, history []model.ChatHistory, currentMes
 		role.Name, role.Gender, role.Age, role.Description)
 
 	systemMessage := "你正在扮演以下角色:\n" + roleDescription +
-		"\n请保持角色设定，用角色的语气和风格回答用户问题。"
+		"\n请保持角色设定，用角色的语气和风格回答用户问题。" + emotionPromptInstruction
 
 	// 添加摘要上下文
 	if existingSummary != "" {
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"Backend-CharacterVerse/model"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// 要求大模型为每句话添加情感标记的提示词
+const emotionPromptInstruction = "\n\n请在每句话开头用方括号标注该句的情感，" +
+	"可选值: [neutral] [happy] [sad] [angry] [surprised] [fear] [excited] [coldness]。" +
+	"情感不变时可以省略标记。除标记外不要输出其他特殊格式。" +
+	"示例: [happy]今天天气真好！[sad]可惜你不能一起来。"
+
+// 仅匹配已知情感，避免误删正文中的方括号内容
+var emotionMarkerRegex = regexp.MustCompile(`\[(neutral|happy|sad|angry|surprised|fear|excited|coldness)\]`)
+
+// EmotionSegment 带情感标记的回复片段
+type EmotionSegment struct {
+	Text    string `json:"text"`
+	Emotion string `json:"emotion,omitempty"`
+}
+
+// ParseEmotionSegments 按情感标记拆分回复文本，initial为首个标记前文本的情感
+func ParseEmotionSegments(text, initial string) []EmotionSegment {
+	var segments []EmotionSegment
+	current := initial
+	last := 0
+
+	appendSegment := func(part string) {
+		if strings.TrimSpace(part) == "" {
+			return
+		}
+		segments = append(segments, EmotionSegment{Text: part, Emotion: current})
+	}
+
+	for _, match := range emotionMarkerRegex.FindAllStringSubmatchIndex(text, -1) {
+		appendSegment(text[last:match[0]])
+		current = text[match[2]:match[3]]
+		last = match[1]
+	}
+	appendSegment(text[last:])
+
+	return segments
+}
+
+// StripEmotionMarkers 去除文本中的情感标记
+func StripEmotionMarkers(text string) string {
+	return emotionMarkerRegex.ReplaceAllString(text, "")
+}
+
+// JoinEmotionSegments 拼接片段文本（不含标记）
+func JoinEmotionSegments(segments []EmotionSegment) string {
+	var builder strings.Builder
+	for _, segment := range segments {
+		builder.WriteString(segment.Text)
+	}
+	return builder.String()
+}
+
+// DominantEmotion 返回文本占比最高的情感
+func DominantEmotion(segments []EmotionSegment) string {
+	weights := make(map[string]int)
+	dominant, maxWeight := "", 0
+	for _, segment := range segments {
+		if segment.Emotion == "" {
+			continue
+		}
+		weights[segment.Emotion] += len([]rune(segment.Text))
+		if weights[segment.Emotion] > maxWeight {
+			dominant, maxWeight = segment.Emotion, weights[segment.Emotion]
+		}
+	}
+	return dominant
+}
+
+// emotionStreamParser 流式解析情感标记，跨片段保持当前情感
+type emotionStreamParser struct {
+	current string
+}
+
+// Feed 解析一个文本片段，返回去除标记后的子片段
+func (p *emotionStreamParser) Feed(fragment string) []EmotionSegment {
+	segments := ParseEmotionSegments(fragment, p.current)
+	if matches := emotionMarkerRegex.FindAllStringSubmatch(fragment, -1); len(matches) > 0 {
+		p.current = matches[len(matches)-1][1]
+	}
+	return segments
+}
+
+// 情感对应的语速、音调、音量调整系数
+var emotionAdjustments = map[string]struct{ speed, pitch, volume float64 }{
+	model.EmotionNeutral:   {1.0, 1.0, 1.0},
+	model.EmotionHappy:     {1.05, 1.1, 1.05},
+	model.EmotionSad:       {0.9, 0.92, 0.9},
+	model.EmotionAngry:     {1.1, 1.05, 1.2},
+	model.EmotionSurprised: {1.1, 1.15, 1.1},
+	model.EmotionFear:      {1.1, 1.05, 0.9},
+	model.EmotionExcited:   {1.15, 1.1, 1.15},
+	model.EmotionColdness:  {0.95, 0.95, 0.9},
+}
+
+// ApplyEmotion 将情感映射为语音风格参数，在角色参数基础上调整
+func ApplyEmotion(base model.VoiceParams, emotion string) model.VoiceParams {
+	adjust, ok := emotionAdjustments[emotion]
+	if !ok {
+		return base
+	}
+
+	params := base
+	params.Emotion = emotion
+	params.Speed = clampVoiceRatio(base.Speed * adjust.speed)
+	params.Pitch = clampVoiceRatio(base.Pitch * adjust.pitch)
+	params.Volume = clampVoiceRatio(base.Volume * adjust.volume)
+	return params
+}
+
+// 将倍率限制在有效范围内并保留两位小数（便于缓存命中）
+func clampVoiceRatio(value float64) float64 {
+	value = math.Max(model.MinVoiceRatio, math.Min(model.MaxVoiceRatio, value))
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"Backend-CharacterVerse/model"
+	"reflect"
+	"testing"
+)
+
+func TestParseEmotionSegments(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		initial string
+		want    []EmotionSegment
+	}{
+		{
+			name: "无标记",
+			text: "你好呀",
+			want: []EmotionSegment{{Text: "你好呀"}},
+		},
+		{
+			name:    "无标记时沿用初始情感",
+			text:    "你好呀",
+			initial: model.EmotionSad,
+			want:    []EmotionSegment{{Text: "你好呀", Emotion: model.EmotionSad}},
+		},
+		{
+			name: "多个标记",
+			text: "[happy]今天天气真好！[sad]可惜你不能一起来。",
+			want: []EmotionSegment{
+				{Text: "今天天气真好！", Emotion: model.EmotionHappy},
+				{Text: "可惜你不能一起来。", Emotion: model.EmotionSad},
+			},
+		},
+		{
+			name:    "首个标记前的文本使用初始情感",
+			text:    "嗯，[angry]别这样！",
+			initial: model.EmotionNeutral,
+			want: []EmotionSegment{
+				{Text: "嗯，", Emotion: model.EmotionNeutral},
+				{Text: "别这样！", Emotion: model.EmotionAngry},
+			},
+		},
+		{
+			name: "跳过空白片段",
+			text: "[happy] [excited]太棒了",
+			want: []EmotionSegment{{Text: "太棒了", Emotion: model.EmotionExcited}},
+		},
+		{
+			name: "未知标记保留在正文中",
+			text: "[happy]看[注释]这里",
+			want: []EmotionSegment{{Text: "看[注释]这里", Emotion: model.EmotionHappy}},
+		},
+		{
+			name: "只有标记",
+			text: "[sad]",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseEmotionSegments(tt.text, tt.initial)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseEmotionSegments(%q, %q) = %+v, 期望 %+v", tt.text, tt.initial, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripEmotionMarkers(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"[happy]你好[sad]再见", "你好再见"},
+		{"没有标记", "没有标记"},
+		{"[Happy]大小写不同不是标记", "[Happy]大小写不同不是标记"},
+		{"[数组]保留", "[数组]保留"},
+	}
+	for _, tt := range tests {
+		if got := StripEmotionMarkers(tt.text); got != tt.want {
+			t.Errorf("StripEmotionMarkers(%q) = %q, 期望 %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDominantEmotion(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []EmotionSegment
+		want     string
+	}{
+		{"空", nil, ""},
+		{"无情感", []EmotionSegment{{Text: "你好"}}, ""},
+		{"按字数累计", []EmotionSegment{
+			{Text: "哈哈", Emotion: model.EmotionHappy},
+			{Text: "唉，真难过", Emotion: model.EmotionSad},
+			{Text: "哈哈哈哈", Emotion: model.EmotionHappy},
+		}, model.EmotionHappy},
+		{"中文按字符而非字节计数", []EmotionSegment{
+			{Text: "好难过", Emotion: model.EmotionSad},
+			{Text: "okay", Emotion: model.EmotionHappy},
+		}, model.EmotionHappy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DominantEmotion(tt.segments); got != tt.want {
+				t.Fatalf("DominantEmotion() = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmotionStreamParserKeepsEmotionAcrossFragments(t *testing.T) {
+	var parser emotionStreamParser
+	fragments := []string{"[happy]你好，", "今天", "[sad]可是", "下雨了"}
+	want := [][]EmotionSegment{
+		{{Text: "你好，", Emotion: model.EmotionHappy}},
+		{{Text: "今天", Emotion: model.EmotionHappy}},
+		{{Text: "可是", Emotion: model.EmotionSad}},
+		{{Text: "下雨了", Emotion: model.EmotionSad}},
+	}
+	for i, fragment := range fragments {
+		if got := parser.Feed(fragment); !reflect.DeepEqual(got, want[i]) {
+			t.Fatalf("Feed(%q) = %+v, 期望 %+v", fragment, got, want[i])
+		}
+	}
+}
+
+func TestApplyEmotion(t *testing.T) {
+	base := model.VoiceParams{Speed: 1.0, Pitch: 1.0, Volume: 1.0}
+
+	if got := ApplyEmotion(base, "unknown"); got != base {
+		t.Fatalf("未知情感应保持原参数, 得到 %+v", got)
+	}
+
+	got := ApplyEmotion(base, model.EmotionSad)
+	want := model.VoiceParams{Speed: 0.9, Pitch: 0.92, Volume: 0.9, Emotion: model.EmotionSad}
+	if got != want {
+		t.Fatalf("ApplyEmotion(sad) = %+v, 期望 %+v", got, want)
+	}
+
+	// 调整后的倍率不超出有效范围
+	fast := ApplyEmotion(model.VoiceParams{Speed: model.MaxVoiceRatio, Pitch: 1, Volume: 1}, model.EmotionExcited)
+	if fast.Speed != model.MaxVoiceRatio {
+		t.Fatalf("语速应限制在 %.2f, 得到 %.2f", model.MaxVoiceRatio, fast.Speed)
+	}
+}
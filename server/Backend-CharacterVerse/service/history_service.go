@@ -3,6 +3,7 @@ package service
 import (
 	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -40,7 +41,7 @@ type UnifiedChatHistory struct {
 	Message      string         `json:"message"`
 	IsUser       bool           `json:"is_user"`
 	MessageType  string         `json:"message_type"`
-	VoiceURL     string         `json:"voice_url"`
+	VoiceURL     model.BlobRef  `json:"voice_url"`
 	ASRText      string         `json:"asr_text"`
 	ResponseType int            `json:"response_type"`
 }
@@ -105,7 +106,7 @@ func (s *HistoryService) GetAllHistories(userID uint) ([]model.ChatHistory, []mo
 	}
 
 	if s.getFromCache(cacheKey, &cacheData) {
-		return cacheData.Text, cacheData.Voice, nil
+		return resolveChatVoiceMessages(cacheData.Text), cacheData.Voice, nil
 	}
 
 	// 缓存未命中，从数据库查询
@@ -127,7 +128,7 @@ func (s *HistoryService) GetAllHistories(userID uint) ([]model.ChatHistory, []mo
 	}{Text: textHistories, Voice: voiceHistories}
 	s.setToCache(cacheKey, cacheData)
 
-	return textHistories, voiceHistories, nil
+	return resolveChatVoiceMessages(textHistories), voiceHistories, nil
 }
 
 // GetUnifiedHistoriesByRole 获取特定角色的统一格式聊天记录（带缓存）
@@ -137,7 +138,7 @@ func (s *HistoryService) GetUnifiedHistoriesByRole(userID, roleID uint) ([]Unifi
 	// 尝试从缓存获取
 	var cachedHistories []UnifiedChatHistory
 	if s.getFromCache(cacheKey, &cachedHistories) {
-		return cachedHistories, nil
+		return resolveVoiceMessages(cachedHistories), nil
 	}
 
 	// 缓存未命中，从数据库查询
@@ -165,7 +166,29 @@ func (s *HistoryService) GetUnifiedHistoriesByRole(userID, roleID uint) ([]Unifi
 	// 保存到缓存
 	s.setToCache(cacheKey, unifiedHistories)
 
-	return unifiedHistories, nil
+	return resolveVoiceMessages(unifiedHistories), nil
+}
+
+// AI语音回复的消息内容为语音对象键，返回前解析为访问地址（缓存中保存对象键）
+func resolveVoiceMessages(histories []UnifiedChatHistory) []UnifiedChatHistory {
+	for i := range histories {
+		h := &histories[i]
+		if h.MessageType == MessageTypeVoice && h.VoiceURL != "" && storage.NormalizeRef(h.Message) == string(h.VoiceURL) {
+			h.Message = h.VoiceURL.URL()
+		}
+	}
+	return histories
+}
+
+// 同 resolveVoiceMessages，用于原始聊天记录
+func resolveChatVoiceMessages(histories []model.ChatHistory) []model.ChatHistory {
+	for i := range histories {
+		h := &histories[i]
+		if h.MessageType == MessageTypeVoice && h.VoiceURL != "" && storage.NormalizeRef(h.Message) == string(h.VoiceURL) {
+			h.Message = h.VoiceURL.URL()
+		}
+	}
+	return histories
 }
 
 // mergeAndConvertHistories 合并文本和语音记录，并按时间排序
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// 图片生成提供方类型
+const (
+	ImageProviderDashScope = "dashscope"
+	ImageProviderStub      = "stub"
+)
+
+// 单次最多生成的候选图片数
+const maxImageCandidates = 4
+
+// ImageGenerateRequest 图片生成请求
+type ImageGenerateRequest struct {
+	Prompt         string
+	NegativePrompt string
+	Count          int    // 候选图片数量
+	Title          string // 角色名称，占位生成器用于绘制首字母
+}
+
+// GeneratedImage 生成结果，URL与Data二选一
+type GeneratedImage struct {
+	URL  string // 提供方返回的临时地址
+	Data []byte // 本地生成的图片数据
+}
+
+// ImageGenerator 图片生成提供方接口
+type ImageGenerator interface {
+	Name() string
+	Generate(ctx context.Context, req ImageGenerateRequest) ([]GeneratedImage, error)
+}
+
+// NewImageGenerator 根据配置创建图片生成提供方
+func NewImageGenerator(cfg *config.Config) (ImageGenerator, error) {
+	switch cfg.ImageProvider {
+	case "", ImageProviderDashScope:
+		return &DashScopeImageGenerator{
+			APIKey: os.Getenv("DASHSCOPE_API_KEY"),
+			Model:  cfg.DashScopeImageModel,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		}, nil
+	case ImageProviderStub:
+		return &StubImageGenerator{}, nil
+	default:
+		return nil, fmt.Errorf("不支持的图片生成提供方: %s", cfg.ImageProvider)
+	}
+}
+
+// DashScopeImageGenerator 阿里云百炼文生图实现（异步任务+轮询）
+type DashScopeImageGenerator struct {
+	APIKey string
+	Model  string
+	Client *http.Client
+}
+
+func (g *DashScopeImageGenerator) Name() string {
+	return ImageProviderDashScope
+}
+
+func (g *DashScopeImageGenerator) Generate(ctx context.Context, req ImageGenerateRequest) ([]GeneratedImage, error) {
+	if g.APIKey == "" {
+		return nil, errors.New("阿里云API密钥未配置")
+	}
+
+	// 调用阿里云API - 创建任务
+	taskID, err := g.createTask(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 轮询获取任务结果
+	urls, err := g.pollTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]GeneratedImage, 0, len(urls))
+	for _, url := range urls {
+		images = append(images, GeneratedImage{URL: url})
+	}
+	return images, nil
+}
+
+// 创建图片生成任务
+func (g *DashScopeImageGenerator) createTask(ctx context.Context, req ImageGenerateRequest) (string, error) {
+	url := "https://dashscope.aliyuncs.com/api/v1/services/aigc/text2image/image-synthesis"
+
+	input := map[string]interface{}{
+		"prompt": req.Prompt,
+	}
+	if req.NegativePrompt != "" {
+		input["negative_prompt"] = req.NegativePrompt
+	}
+	payload := map[string]interface{}{
+		"model": g.Model,
+		"input": input,
+		"parameters": map[string]interface{}{
+			"size":          "1024*1024",
+			"n":             req.Count,
+			"prompt_extend": true, // 开启智能提示词优化
+		},
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", err
+	}
+
+	// 添加必要请求头
+	httpReq.Header.Set("Authorization", "Bearer "+g.APIKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-DashScope-Async", "enable") // 必须添加的异步头
+
+	resp, err := g.Client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API请求失败: 状态码 %d, 响应: %s", resp.StatusCode, string(body))
+	}
+
+	// 解析任务ID
+	var result struct {
+		Output struct {
+			TaskID string `json:"task_id"`
+		} `json:"output"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析任务响应失败: %v, 响应体: %s", err, string(body))
+	}
+	if result.Code != "" {
+		return "", fmt.Errorf("API错误: %s - %s", result.Code, result.Message)
+	}
+	if result.Output.TaskID == "" {
+		return "", errors.New("未获取到任务ID")
+	}
+
+	log.Printf("任务创建成功, ID: %s", result.Output.TaskID)
+	return result.Output.TaskID, nil
+}
+
+// 轮询任务结果，返回全部成功生成的图片地址
+func (g *DashScopeImageGenerator) pollTask(ctx context.Context, taskID string) ([]string, error) {
+	url := fmt.Sprintf("https://dashscope.aliyuncs.com/api/v1/tasks/%s", taskID)
+
+	// 设置轮询参数
+	maxAttempts := 50        // 最大尝试次数
+	delay := 5 * time.Second // 每次轮询间隔
+
+	for i := 0; i < maxAttempts; i++ {
+		httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+g.APIKey)
+
+		resp, err := g.Client.Do(httpReq)
+		if err != nil {
+			return nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("任务查询失败: 状态码 %d", resp.StatusCode)
+		}
+
+		// 解析任务状态
+		var status struct {
+			Output struct {
+				TaskStatus string `json:"task_status"`
+				Results    []struct {
+					URL string `json:"url"`
+				} `json:"results"`
+			} `json:"output"`
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			log.Printf("解析任务状态失败: %v, 响应体: %s", err, string(body))
+			return nil, fmt.Errorf("解析任务状态失败: %v", err)
+		}
+
+		log.Printf("任务状态: %s, 等待中... (尝试 %d/%d)", status.Output.TaskStatus, i+1, maxAttempts)
+
+		switch status.Output.TaskStatus {
+		case "SUCCEEDED":
+			var urls []string
+			for _, result := range status.Output.Results {
+				if result.URL != "" {
+					urls = append(urls, result.URL)
+				}
+			}
+			if len(urls) == 0 {
+				return nil, errors.New("任务成功但未获取到图片URL")
+			}
+			log.Printf("任务成功完成, 图片数: %d", len(urls))
+			return urls, nil
+
+		case "FAILED", "CANCELED":
+			return nil, fmt.Errorf("任务失败: %s - %s", status.Code, status.Message)
+
+		default: // PENDING, RUNNING
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+	}
+
+	return nil, errors.New("任务超时未完成")
+}
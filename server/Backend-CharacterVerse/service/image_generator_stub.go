@@ -0,0 +1,138 @@
+package service
+
+import (
+	"Backend-CharacterVerse/utils"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"unicode"
+)
+
+// 占位头像参数
+const (
+	stubImageSize   = 512
+	stubGlyphWidth  = 5
+	stubGlyphHeight = 7
+	stubMaxLetters  = 2
+)
+
+// 5x7点阵字体，用于在占位头像上绘制首字母
+var stubGlyphs = map[rune][stubGlyphHeight]string{
+	'A': {".###.", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'B': {"####.", "#...#", "#...#", "####.", "#...#", "#...#", "####."},
+	'C': {".###.", "#...#", "#....", "#....", "#....", "#...#", ".###."},
+	'D': {"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	'E': {"#####", "#....", "#....", "####.", "#....", "#....", "#####"},
+	'F': {"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+	'G': {".###.", "#...#", "#....", "#.###", "#...#", "#...#", ".####"},
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'I': {".###.", "..#..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'J': {"..###", "...#.", "...#.", "...#.", "...#.", "#..#.", ".##.."},
+	'K': {"#...#", "#..#.", "#.#..", "##...", "#.#..", "#..#.", "#...#"},
+	'L': {"#....", "#....", "#....", "#....", "#....", "#....", "#####"},
+	'M': {"#...#", "##.##", "#.#.#", "#.#.#", "#...#", "#...#", "#...#"},
+	'N': {"#...#", "#...#", "##..#", "#.#.#", "#..##", "#...#", "#...#"},
+	'O': {".###.", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'P': {"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	'Q': {".###.", "#...#", "#...#", "#...#", "#.#.#", "#..#.", ".##.#"},
+	'R': {"####.", "#...#", "#...#", "####.", "#.#..", "#..#.", "#...#"},
+	'S': {".####", "#....", "#....", ".###.", "....#", "....#", "####."},
+	'T': {"#####", "..#..", "..#..", "..#..", "..#..", "..#..", "..#.."},
+	'U': {"#...#", "#...#", "#...#", "#...#", "#...#", "#...#", ".###."},
+	'V': {"#...#", "#...#", "#...#", "#...#", "#...#", ".#.#.", "..#.."},
+	'W': {"#...#", "#...#", "#...#", "#.#.#", "#.#.#", "#.#.#", ".#.#."},
+	'X': {"#...#", "#...#", ".#.#.", "..#..", ".#.#.", "#...#", "#...#"},
+	'Y': {"#...#", "#...#", ".#.#.", "..#..", "..#..", "..#..", "..#.."},
+	'Z': {"#####", "....#", "...#.", "..#..", ".#...", "#....", "#####"},
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+	'?': {".###.", "#...#", "....#", "...#.", "..#..", ".....", "..#.."},
+}
+
+// StubImageGenerator 本地占位生成器：纯色背景上绘制角色名首字母，无需外部服务
+type StubImageGenerator struct{}
+
+func (g *StubImageGenerator) Name() string {
+	return ImageProviderStub
+}
+
+func (g *StubImageGenerator) Generate(ctx context.Context, req ImageGenerateRequest) ([]GeneratedImage, error) {
+	letters := stubInitials(req.Title)
+
+	images := make([]GeneratedImage, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
+		// 同一角色的候选图使用不同背景色
+		sum := sha256.Sum256([]byte(req.Title + req.Prompt + string(rune('a'+i))))
+		background := color.RGBA{R: 64 + sum[0]%128, G: 64 + sum[1]%128, B: 64 + sum[2]%128, A: 255}
+
+		img := image.NewRGBA(image.Rect(0, 0, stubImageSize, stubImageSize))
+		draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+		drawStubText(img, letters, color.White)
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		images = append(images, GeneratedImage{Data: buf.Bytes()})
+	}
+	return images, nil
+}
+
+// 取名称的首字母（中文取拼音首字母），最多两个
+func stubInitials(title string) string {
+	var letters []rune
+	for _, r := range strings.ToUpper(utils.PinyinInitials(title)) {
+		if _, ok := stubGlyphs[r]; ok && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			letters = append(letters, r)
+		}
+		if len(letters) == stubMaxLetters {
+			break
+		}
+	}
+	if len(letters) == 0 {
+		return "?"
+	}
+	return string(letters)
+}
+
+// 将文本按点阵放大后居中绘制
+func drawStubText(img *image.RGBA, text string, c color.Color) {
+	runes := []rune(text)
+	// 字间距为一个点，文本宽度约占画布一半
+	columns := len(runes)*(stubGlyphWidth+1) - 1
+	scale := img.Bounds().Dx() / 2 / columns
+	if maxScale := img.Bounds().Dy() / 2 / stubGlyphHeight; scale > maxScale {
+		scale = maxScale
+	}
+
+	originX := (img.Bounds().Dx() - columns*scale) / 2
+	originY := (img.Bounds().Dy() - stubGlyphHeight*scale) / 2
+	fill := image.NewUniform(c)
+
+	for i, r := range runes {
+		glyph := stubGlyphs[r]
+		for row, line := range glyph {
+			for col, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				x := originX + (i*(stubGlyphWidth+1)+col)*scale
+				y := originY + row*scale
+				draw.Draw(img, image.Rect(x, y, x+scale, y+scale), fill, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 额度类型
+const (
+	QuotaKindTTS = "tts" // 按字符计
+	QuotaKindASR = "asr" // 按秒计
+)
+
+// 额度记录保留时间，略长于一天以覆盖跨日请求
+const quotaKeyTTL = 48 * time.Hour
+
+var ErrQuotaExceeded = errors.New("今日额度已用完")
+
+// 生成每日额度缓存键
+func quotaKey(kind string, userID uint) string {
+	return fmt.Sprintf("quota:%s:%d:%s", kind, userID, time.Now().Format("20060102"))
+}
+
+// GetDailyUsage 获取用户当日已用额度
+func GetDailyUsage(kind string, userID uint) (int64, error) {
+	ctx := context.Background()
+	used, err := database.RedisClient.Get(ctx, quotaKey(kind, userID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return used, nil
+}
+
+// ConsumeQuota 扣减额度，超出上限时回滚并返回 ErrQuotaExceeded
+func ConsumeQuota(kind string, userID uint, amount, limit int64) error {
+	ctx := context.Background()
+	key := quotaKey(kind, userID)
+
+	used, err := database.RedisClient.IncrBy(ctx, key, amount).Result()
+	if err != nil {
+		return fmt.Errorf("额度记录失败: %w", err)
+	}
+	database.RedisClient.Expire(ctx, key, quotaKeyTTL)
+
+	if limit > 0 && used > limit {
+		database.RedisClient.DecrBy(ctx, key, amount)
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// RefundQuota 退还已扣减的额度（如调用失败时）
+func RefundQuota(kind string, userID uint, amount int64) {
+	ctx := context.Background()
+	database.RedisClient.DecrBy(ctx, quotaKey(kind, userID), amount)
+}
+
+// AddQuotaUsage 记录用量但不做上限检查（用于事后才能得知用量的场景）
+func AddQuotaUsage(kind string, userID uint, amount int64) {
+	ctx := context.Background()
+	key := quotaKey(kind, userID)
+	database.RedisClient.IncrBy(ctx, key, amount)
+	database.RedisClient.Expire(ctx, key, quotaKeyTTL)
+}
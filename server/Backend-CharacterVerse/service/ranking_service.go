@@ -0,0 +1,197 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 排行计算参数：统计窗口和半衰期
+type rankingWindow struct {
+	window   time.Duration
+	halfLife time.Duration
+}
+
+var (
+	hotRankingWindow      = rankingWindow{window: 7 * 24 * time.Hour, halfLife: 48 * time.Hour}
+	trendingRankingWindow = rankingWindow{window: 24 * time.Hour, halfLife: 6 * time.Hour}
+)
+
+// 各项指标权重：消息数按时间衰减累加，独立用户和点赞更能反映真实热度
+const (
+	rankingUserWeight = 3.0
+	rankingLikeWeight = 5.0
+)
+
+// 分布式锁，避免多实例重复计算
+const rankingLockKey = "ranking:lock"
+
+// 单个角色在窗口内的活跃度
+type roleActivity struct {
+	RoleID       uint
+	MessageCount int64
+	UniqueUsers  int64
+	LikeCount    int64
+	Decayed      float64 // 按时间衰减后的消息数
+	DecayedLikes float64 // 按时间衰减后的点赞数
+}
+
+// StartRankingJob 启动后台排行计算任务
+func StartRankingJob() {
+	interval := time.Duration(config.LoadConfig().RankingRefreshMinutes) * time.Minute
+	if interval <= 0 {
+		log.Println("角色排行任务已禁用")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			runRankingJob(interval)
+			<-ticker.C
+		}
+	}()
+}
+
+// 获取锁后执行一次计算
+func runRankingJob(interval time.Duration) {
+	ctx := context.Background()
+	// 锁的有效期略短于周期，实例崩溃后下个周期可以重新获取
+	acquired, err := database.RedisClient.SetNX(ctx, rankingLockKey, time.Now().Unix(), interval*9/10).Result()
+	if err != nil {
+		log.Printf("获取排行任务锁失败: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	start := time.Now()
+	count, err := RefreshRoleRankings(start)
+	if err != nil {
+		log.Printf("角色排行计算失败: %v", err)
+		return
+	}
+	log.Printf("角色排行已更新: 角色数=%d, 耗时=%v", count, time.Since(start))
+}
+
+// RefreshRoleRankings 根据近期聊天量、独立用户数和点赞数重新计算排行
+func RefreshRoleRankings(now time.Time) (int, error) {
+	hot, err := collectRoleActivity(now, hotRankingWindow)
+	if err != nil {
+		return 0, err
+	}
+	trending, err := collectRoleActivity(now, trendingRankingWindow)
+	if err != nil {
+		return 0, err
+	}
+
+	rankings := make(map[uint]*model.RoleRanking, len(hot))
+	for roleID, activity := range hot {
+		rankings[roleID] = &model.RoleRanking{
+			RoleID:       roleID,
+			HotScore:     activityScore(activity),
+			MessageCount: activity.MessageCount,
+			UniqueUsers:  activity.UniqueUsers,
+			RecentLikes:  activity.LikeCount,
+			UpdatedAt:    now,
+		}
+	}
+	// 飙升窗口是热门窗口的子集
+	for roleID, activity := range trending {
+		if ranking, ok := rankings[roleID]; ok {
+			ranking.TrendingScore = activityScore(activity)
+		}
+	}
+
+	list := make([]model.RoleRanking, 0, len(rankings))
+	for _, ranking := range rankings {
+		list = append(list, *ranking)
+	}
+
+	// 整表替换，已冷却的角色自然移出排行
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.RoleRanking{}).Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(list, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(list), nil
+}
+
+// 综合得分
+func activityScore(activity *roleActivity) float64 {
+	return activity.Decayed + float64(activity.UniqueUsers)*rankingUserWeight + activity.DecayedLikes*rankingLikeWeight
+}
+
+// 统计窗口内各角色的聊天量、独立用户数和点赞数，按半衰期做指数衰减
+func collectRoleActivity(now time.Time, w rankingWindow) (map[uint]*roleActivity, error) {
+	since := now.Add(-w.window)
+	decay := math.Ln2 / w.halfLife.Seconds()
+
+	var messages []struct {
+		RoleID       uint
+		MessageCount int64
+		UniqueUsers  int64
+		Decayed      float64
+	}
+	err := database.DB.Model(&model.ChatHistory{}).
+		Select("role_id, COUNT(*) AS message_count, COUNT(DISTINCT user_id) AS unique_users, "+
+			"SUM(EXP(-? * TIMESTAMPDIFF(SECOND, created_at, ?))) AS decayed", decay, now).
+		Where("is_user = ? AND created_at >= ?", true, since).
+		Group("role_id").
+		Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var likes []struct {
+		RoleID    uint
+		LikeCount int64
+		Decayed   float64
+	}
+	err = database.DB.Model(&model.RoleLike{}).
+		Select("role_id, COUNT(*) AS like_count, SUM(EXP(-? * TIMESTAMPDIFF(SECOND, created_at, ?))) AS decayed", decay, now).
+		Where("created_at >= ?", since).
+		Group("role_id").
+		Scan(&likes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	activities := make(map[uint]*roleActivity, len(messages))
+	get := func(roleID uint) *roleActivity {
+		activity, ok := activities[roleID]
+		if !ok {
+			activity = &roleActivity{RoleID: roleID}
+			activities[roleID] = activity
+		}
+		return activity
+	}
+	for _, m := range messages {
+		activity := get(m.RoleID)
+		activity.MessageCount = m.MessageCount
+		activity.UniqueUsers = m.UniqueUsers
+		activity.Decayed = m.Decayed
+	}
+	for _, l := range likes {
+		activity := get(l.RoleID)
+		activity.LikeCount = l.LikeCount
+		activity.DecayedLikes = l.Decayed
+	}
+	return activities, nil
+}
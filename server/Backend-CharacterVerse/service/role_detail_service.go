@@ -0,0 +1,124 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"fmt"
+	"time"
+)
+
+// 头像生成状态
+const (
+	AvatarStatusPending   = model.AvatarJobPending
+	AvatarStatusRunning   = model.AvatarJobRunning
+	AvatarStatusSucceeded = model.AvatarJobSucceeded
+	AvatarStatusFailed    = model.AvatarJobFailed
+)
+
+// 没有生成任务记录的旧角色，超过该时间仍无头像视为生成失败
+const avatarGenerationTimeout = 15 * time.Minute
+
+// RoleStats 角色使用统计
+type RoleStats struct {
+	ChatCount    int64      `json:"chat_count"`     // 消息总数
+	UniqueUsers  int64      `json:"unique_users"`   // 对话过的用户数
+	LastActiveAt *time.Time `json:"last_active_at"` // 最近一次对话时间
+}
+
+// RoleDetail 角色详情
+type RoleDetail struct {
+	Role            model.Role `json:"role"`
+	CreatorUsername string     `json:"creator_username"`
+	AvatarStatus    string     `json:"avatar_status"`
+	Liked           bool       `json:"liked"`     // 当前用户是否已点赞
+	Favorited       bool       `json:"favorited"` // 当前用户是否已收藏
+
+	Lineage []RoleLineageItem `json:"lineage,omitempty"` // 派生链，从直接来源到最早的祖先
+	RoleStats
+}
+
+func roleStatsKey(roleID uint) string {
+	return fmt.Sprintf("role:stats:%d", roleID)
+}
+
+// GetRoleDetail 获取角色详情（私有角色仅创建者可查看）
+func GetRoleDetail(roleID, viewerID uint) (*RoleDetail, error) {
+	role, err := GetAccessibleRole(roleID, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &RoleDetail{
+		Role:         *role,
+		AvatarStatus: getAvatarStatus(role),
+	}
+
+	var creator model.User
+	if err := database.DB.Select("username").First(&creator, role.UserID).Error; err == nil {
+		detail.CreatorUsername = creator.Username
+	}
+
+	roleTags, err := loadRoleTags([]uint{roleID})
+	if err != nil {
+		return nil, err
+	}
+	detail.Role.Tags = roleTags[roleID]
+
+	detail.Liked, detail.Favorited = GetRoleInteraction(roleID, viewerID)
+
+	detail.Lineage = getRoleLineage(role, viewerID)
+
+	stats, err := getRoleStats(roleID)
+	if err != nil {
+		return nil, err
+	}
+	detail.RoleStats = *stats
+
+	return detail, nil
+}
+
+// 获取头像生成状态：以最近一次生成任务为准，旧角色根据头像URL和创建时间推断
+func getAvatarStatus(role *model.Role) string {
+	if job, err := getLatestAvatarJob(role.ID); err == nil && job != nil {
+		return job.Status
+	}
+
+	if role.AvatarURL != "" {
+		return AvatarStatusSucceeded
+	}
+	if time.Since(role.CreatedAt) < avatarGenerationTimeout {
+		return AvatarStatusPending
+	}
+	return AvatarStatusFailed
+}
+
+// 统计角色的对话数据（带缓存）
+func getRoleStats(roleID uint) (*RoleStats, error) {
+	cache := HistoryService{}
+	cacheKey := roleStatsKey(roleID)
+
+	var stats RoleStats
+	if cache.getFromCache(cacheKey, &stats) {
+		return &stats, nil
+	}
+
+	var row struct {
+		ChatCount    int64
+		UniqueUsers  int64
+		LastActiveAt *time.Time
+	}
+	if err := database.DB.Model(&model.ChatHistory{}).
+		Select("COUNT(*) AS chat_count, COUNT(DISTINCT user_id) AS unique_users, MAX(created_at) AS last_active_at").
+		Where("role_id = ?", roleID).
+		Scan(&row).Error; err != nil {
+		return nil, err
+	}
+
+	stats = RoleStats{
+		ChatCount:    row.ChatCount,
+		UniqueUsers:  row.UniqueUsers,
+		LastActiveAt: row.LastActiveAt,
+	}
+	cache.setToCache(cacheKey, stats)
+	return &stats, nil
+}
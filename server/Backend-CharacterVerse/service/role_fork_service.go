@@ -0,0 +1,147 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// 派生链最多向上追溯的层数
+const maxForkLineageDepth = 10
+
+// ForkRoleRequest 派生角色的可选参数
+type ForkRoleRequest struct {
+	Name       string `json:"name"`       // 新角色名称，默认沿用原名
+	Visibility string `json:"visibility"` // 新角色可见性，默认私有，便于修改后再公开
+}
+
+// RoleLineageItem 派生链中的一个祖先角色
+type RoleLineageItem struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name,omitempty"`
+	CreatorUsername string `json:"creator_username,omitempty"`
+	Available       bool   `json:"available"` // 已删除或不可见的祖先仅返回ID
+}
+
+// ForkRole 将公开角色复制为当前用户的新角色，保留来源关联
+func ForkRole(roleID, userID uint, req ForkRoleRequest) (uint, error) {
+	source, err := database.GetRoleByID(roleID)
+	if err != nil {
+		return 0, err
+	}
+
+	// 创建者可以复制自己的任意角色，其他人只能派生允许派生的公开角色
+	if source.UserID != userID {
+		if source.Visibility != model.VisibilityPublic {
+			return 0, errors.New("只能派生公开角色")
+		}
+		if !source.AllowFork {
+			return 0, errors.New("该角色的创建者不允许派生")
+		}
+	}
+
+	name := source.Name
+	if req.Name != "" {
+		name = req.Name
+	}
+	visibility := model.VisibilityPrivate
+	if req.Visibility != "" {
+		if !model.IsValidVisibility(req.Visibility) {
+			return 0, fmt.Errorf("无效的可见性，有效值为: %v", model.ValidVisibilities)
+		}
+		visibility = req.Visibility
+	}
+
+	// 来源角色使用的克隆声音属于原创建者，派生者无权使用时换成内置声音
+	voiceType := source.VoiceType
+	if ValidateVoiceType(voiceType, userID) != nil {
+		voiceType = defaultVoiceForGender(source.Gender)
+	}
+
+	tags, err := getRoleTagNames(source.ID)
+	if err != nil {
+		return 0, err
+	}
+	if len(tags) == 0 {
+		tags = []string{source.Tag}
+	}
+
+	forkedFromID := source.ID
+	fork := model.Role{
+		Name:         name,
+		Description:  source.Description,
+		UserID:       userID,
+		Gender:       source.Gender,
+		Age:          source.Age,
+		VoiceType:    voiceType,
+		AvatarURL:    source.AvatarURL,
+		Tag:          source.Tag,
+		VoiceSpeed:   source.VoiceSpeed,
+		VoicePitch:   source.VoicePitch,
+		VoiceVolume:  source.VoiceVolume,
+		VoiceEmotion: source.VoiceEmotion,
+		Visibility:   visibility,
+		ForkedFromID: &forkedFromID,
+		AllowFork:    true,
+
+		AvatarThumbnails: source.AvatarThumbnails,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fork).Error; err != nil {
+			return err
+		}
+		if err := setRoleTags(tx, fork.ID, tags); err != nil {
+			return err
+		}
+		return tx.Model(&model.Role{}).Where("id = ?", source.ID).
+			UpdateColumn("fork_count", gorm.Expr("fork_count + 1")).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reindexRole(fork.ID)
+	return fork.ID, nil
+}
+
+// 按角色性别选择内置声音
+func defaultVoiceForGender(gender string) string {
+	if gender == "男" {
+		return model.VoiceMagneticCourseware
+	}
+	return model.VoiceGentleTeacher
+}
+
+// getRoleLineage 获取角色的派生链，从直接来源到最早的祖先
+func getRoleLineage(role *model.Role, viewerID uint) []RoleLineageItem {
+	var lineage []RoleLineageItem
+	visited := map[uint]bool{role.ID: true}
+
+	parentID := role.ForkedFromID
+	for parentID != nil && len(lineage) < maxForkLineageDepth && !visited[*parentID] {
+		visited[*parentID] = true
+
+		var parent model.Role
+		if err := database.DB.Unscoped().First(&parent, *parentID).Error; err != nil {
+			lineage = append(lineage, RoleLineageItem{ID: *parentID})
+			break
+		}
+
+		item := RoleLineageItem{ID: parent.ID}
+		if !parent.DeletedAt.Valid && parent.CanBeAccessedBy(viewerID) {
+			item.Name = parent.Name
+			item.Available = true
+			var creator model.User
+			if err := database.DB.Select("username").First(&creator, parent.UserID).Error; err == nil {
+				item.CreatorUsername = creator.Username
+			}
+		}
+		lineage = append(lineage, item)
+		parentID = parent.ForkedFromID
+	}
+	return lineage
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 角色计数列
+const (
+	roleLikeCountColumn     = "like_count"
+	roleFavoriteCountColumn = "favorite_count"
+)
+
+// LikeRole 点赞角色，返回最新点赞数
+func LikeRole(roleID, userID uint) (int64, error) {
+	return toggleRoleInteraction(roleID, userID, &model.RoleLike{UserID: userID, RoleID: roleID}, roleLikeCountColumn, true)
+}
+
+// UnlikeRole 取消点赞，返回最新点赞数
+func UnlikeRole(roleID, userID uint) (int64, error) {
+	return toggleRoleInteraction(roleID, userID, &model.RoleLike{}, roleLikeCountColumn, false)
+}
+
+// FavoriteRole 收藏角色，返回最新收藏数
+func FavoriteRole(roleID, userID uint) (int64, error) {
+	return toggleRoleInteraction(roleID, userID, &model.RoleFavorite{UserID: userID, RoleID: roleID}, roleFavoriteCountColumn, true)
+}
+
+// UnfavoriteRole 取消收藏，返回最新收藏数
+func UnfavoriteRole(roleID, userID uint) (int64, error) {
+	return toggleRoleInteraction(roleID, userID, &model.RoleFavorite{}, roleFavoriteCountColumn, false)
+}
+
+// 添加或删除点赞/收藏记录，并同步角色上的计数
+func toggleRoleInteraction(roleID, userID uint, record interface{}, counterColumn string, add bool) (int64, error) {
+	if _, err := GetAccessibleRole(roleID, userID); err != nil {
+		return 0, err
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var result *gorm.DB
+		if add {
+			// 重复操作不报错也不重复计数
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		} else {
+			result = tx.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(record)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		expr := gorm.Expr(counterColumn + " + 1")
+		if !add {
+			expr = gorm.Expr("GREATEST(" + counterColumn + " - 1, 0)")
+		}
+		return tx.Model(&model.Role{}).Where("id = ?", roleID).UpdateColumn(counterColumn, expr).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = database.DB.Model(&model.Role{}).Where("id = ?", roleID).Select(counterColumn).Scan(&count).Error
+	return count, err
+}
+
+// GetRoleInteraction 查询用户是否点赞、收藏了角色
+func GetRoleInteraction(roleID, userID uint) (liked, favorited bool) {
+	if userID == 0 {
+		return false, false
+	}
+
+	var count int64
+	database.DB.Model(&model.RoleLike{}).Where("user_id = ? AND role_id = ?", userID, roleID).Count(&count)
+	liked = count > 0
+
+	database.DB.Model(&model.RoleFavorite{}).Where("user_id = ? AND role_id = ?", userID, roleID).Count(&count)
+	favorited = count > 0
+	return
+}
+
+// GetFavoriteRoles 获取用户收藏的角色
+func GetFavoriteRoles(userID uint, pagination model.Pagination) (*model.PaginatedResult, error) {
+	query := database.DB.
+		Joins("JOIN role_favorites ON role_favorites.role_id = roles.id").
+		Where("role_favorites.user_id = ?", userID).
+		Scopes(visibleRoles(userID))
+
+	return paginateRoles(query, pagination)
+}
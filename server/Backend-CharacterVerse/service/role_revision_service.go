@@ -0,0 +1,330 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 参与版本对比和恢复的字段，顺序即对比结果的顺序
+var roleRevisionFields = []string{
+	"name", "description", "gender", "age", "voice_type", "tag", "tags",
+	"voice_speed", "voice_pitch", "voice_volume", "voice_emotion", "visibility", "allow_fork",
+}
+
+// RoleRevisionSummary 版本列表项
+type RoleRevisionSummary struct {
+	Revision  int       `json:"revision"`
+	Name      string    `json:"name"`
+	EditorID  uint      `json:"editor_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"` // 是否为当前版本
+}
+
+// DiffLine 文本逐行对比结果
+type DiffLine struct {
+	Op   string `json:"op"` // equal, insert, delete
+	Text string `json:"text"`
+}
+
+// RevisionFieldDiff 单个字段的变化
+type RevisionFieldDiff struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	Lines []DiffLine  `json:"lines,omitempty"` // 描述字段的逐行对比
+}
+
+// 获取当前用户拥有的角色
+func getOwnedRole(db *gorm.DB, roleID, userID uint) (*model.Role, error) {
+	var role model.Role
+	if err := db.Where("id = ? AND user_id = ?", roleID, userID).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("角色不存在或您无权操作此角色")
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// 根据角色当前状态生成快照
+func buildRoleSnapshot(role *model.Role, editorID uint) (*model.RoleRevision, error) {
+	tags, err := getRoleTagNames(role.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.RoleRevision{
+		RoleID:       role.ID,
+		Revision:     role.Revision,
+		EditorID:     editorID,
+		Name:         role.Name,
+		Description:  role.Description,
+		Gender:       role.Gender,
+		Age:          role.Age,
+		VoiceType:    role.VoiceType,
+		Tag:          role.Tag,
+		Tags:         strings.Join(tags, ","),
+		VoiceSpeed:   role.VoiceSpeed,
+		VoicePitch:   role.VoicePitch,
+		VoiceVolume:  role.VoiceVolume,
+		VoiceEmotion: role.VoiceEmotion,
+		Visibility:   role.Visibility,
+		AllowFork:    role.AllowFork,
+	}, nil
+}
+
+// applyRoleRevision 保存修改前的快照并以乐观锁写入新版本
+// updates 为要更新的列，tags 非nil时同时替换标签
+func applyRoleRevision(tx *gorm.DB, role *model.Role, editorID uint, updates map[string]interface{}, tags []string) error {
+	snapshot, err := buildRoleSnapshot(role, editorID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(snapshot).Error; err != nil {
+		return err
+	}
+
+	columns := make(map[string]interface{}, len(updates)+1)
+	for key, value := range updates {
+		columns[key] = value
+	}
+	columns["revision"] = role.Revision + 1
+
+	result := tx.Model(&model.Role{}).
+		Where("id = ? AND revision = ?", role.ID, role.Revision).
+		Updates(columns)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("角色已被修改，请刷新后重试")
+	}
+
+	if tags != nil {
+		return setRoleTags(tx, role.ID, tags)
+	}
+	// 标签使用次数只计公开角色，可见性变化时需重新统计
+	if visibility, ok := updates["visibility"]; ok && visibility != role.Visibility {
+		return refreshRoleTagUsage(tx, role.ID)
+	}
+	return nil
+}
+
+// ListRoleRevisions 获取角色的版本列表（仅创建者），按版本号倒序
+func ListRoleRevisions(roleID, userID uint) ([]RoleRevisionSummary, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []model.RoleRevision
+	if err := database.DB.Select("revision, name, editor_id, created_at").
+		Where("role_id = ?", roleID).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := []RoleRevisionSummary{{
+		Revision:  role.Revision,
+		Name:      role.Name,
+		EditorID:  role.UserID,
+		CreatedAt: role.UpdatedAt,
+		Current:   true,
+	}}
+	for _, revision := range revisions {
+		summaries = append(summaries, RoleRevisionSummary{
+			Revision:  revision.Revision,
+			Name:      revision.Name,
+			EditorID:  revision.EditorID,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+	return summaries, nil
+}
+
+// GetRoleRevision 获取指定版本的完整内容（仅创建者），当前版本直接由角色生成
+func GetRoleRevision(roleID, userID uint, revision int) (*model.RoleRevision, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return loadRoleRevision(role, revision)
+}
+
+func loadRoleRevision(role *model.Role, revision int) (*model.RoleRevision, error) {
+	if revision == role.Revision {
+		snapshot, err := buildRoleSnapshot(role, role.UserID)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.CreatedAt = role.UpdatedAt
+		return snapshot, nil
+	}
+
+	var snapshot model.RoleRevision
+	if err := database.DB.Where("role_id = ? AND revision = ?", role.ID, revision).First(&snapshot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("版本%d不存在", revision)
+		}
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// 快照的字段值，键与 roleRevisionFields 对应
+func revisionFieldValues(r *model.RoleRevision) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          r.Name,
+		"description":   r.Description,
+		"gender":        r.Gender,
+		"age":           r.Age,
+		"voice_type":    r.VoiceType,
+		"tag":           r.Tag,
+		"tags":          r.Tags,
+		"voice_speed":   r.VoiceSpeed,
+		"voice_pitch":   r.VoicePitch,
+		"voice_volume":  r.VoiceVolume,
+		"voice_emotion": r.VoiceEmotion,
+		"visibility":    r.Visibility,
+		"allow_fork":    r.AllowFork,
+	}
+}
+
+// DiffRoleRevisions 对比两个版本（仅创建者），to 为0时与当前版本对比
+func DiffRoleRevisions(roleID, userID uint, from, to int) ([]RevisionFieldDiff, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		to = role.Revision
+	}
+
+	fromRevision, err := loadRoleRevision(role, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := loadRoleRevision(role, to)
+	if err != nil {
+		return nil, err
+	}
+
+	fromValues := revisionFieldValues(fromRevision)
+	toValues := revisionFieldValues(toRevision)
+
+	diffs := []RevisionFieldDiff{}
+	for _, field := range roleRevisionFields {
+		if reflect.DeepEqual(fromValues[field], toValues[field]) {
+			continue
+		}
+		diff := RevisionFieldDiff{Field: field, From: fromValues[field], To: toValues[field]}
+		if field == "description" {
+			diff.Lines = diffLines(fromRevision.Description, toRevision.Description)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// RestoreRoleRevision 将角色恢复到指定版本（仅创建者），恢复操作本身会产生一个新版本
+func RestoreRoleRevision(roleID, userID uint, revision int) (int, error) {
+	role, err := getOwnedRole(database.DB, roleID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if revision == role.Revision {
+		return 0, errors.New("该版本已是当前版本")
+	}
+
+	snapshot, err := loadRoleRevision(role, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	// 历史版本使用的声音可能已被删除或停用
+	if err := ValidateVoiceType(snapshot.VoiceType, userID); err != nil {
+		return 0, fmt.Errorf("无法恢复该版本: %w", err)
+	}
+
+	updates := revisionFieldValues(snapshot)
+	delete(updates, "tags")
+
+	var tags []string
+	if snapshot.Tags != "" {
+		tags = strings.Split(snapshot.Tags, ",")
+	} else {
+		tags = []string{snapshot.Tag}
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return applyRoleRevision(tx, role, userID, updates, tags)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reindexRole(roleID)
+	return role.Revision + 1, nil
+}
+
+// diffLines 基于最长公共子序列的逐行对比
+func diffLines(from, to string) []DiffLine {
+	a := splitLines(from)
+	b := splitLines(to)
+
+	// lcs[i][j] 为 a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: "equal", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: "delete", Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: "insert", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: "delete", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: "insert", Text: b[j]})
+	}
+	return lines
+}
+
+// 按行拆分文本，空文本没有任何行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
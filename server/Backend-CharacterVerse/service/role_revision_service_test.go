@@ -0,0 +1,81 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []DiffLine
+	}{
+		{
+			name: "内容相同",
+			from: "a\nb",
+			to:   "a\nb",
+			want: []DiffLine{{Op: "equal", Text: "a"}, {Op: "equal", Text: "b"}},
+		},
+		{
+			name: "末尾新增",
+			from: "a",
+			to:   "a\nb",
+			want: []DiffLine{{Op: "equal", Text: "a"}, {Op: "insert", Text: "b"}},
+		},
+		{
+			name: "中间删除",
+			from: "a\nb\nc",
+			to:   "a\nc",
+			want: []DiffLine{{Op: "equal", Text: "a"}, {Op: "delete", Text: "b"}, {Op: "equal", Text: "c"}},
+		},
+		{
+			name: "修改一行时先删后增",
+			from: "a\nb\nc",
+			to:   "a\nB\nc",
+			want: []DiffLine{
+				{Op: "equal", Text: "a"},
+				{Op: "delete", Text: "b"},
+				{Op: "insert", Text: "B"},
+				{Op: "equal", Text: "c"},
+			},
+		},
+		{
+			name: "从空文本开始",
+			from: "",
+			to:   "a\nb",
+			want: []DiffLine{{Op: "insert", Text: "a"}, {Op: "insert", Text: "b"}},
+		},
+		{
+			name: "清空文本",
+			from: "a",
+			to:   "",
+			want: []DiffLine{{Op: "delete", Text: "a"}},
+		},
+		{
+			name: "保留空行",
+			from: "a\n\nb",
+			to:   "a\nb",
+			want: []DiffLine{{Op: "equal", Text: "a"}, {Op: "delete", Text: ""}, {Op: "equal", Text: "b"}},
+		},
+		{
+			name: "保留最长公共部分",
+			from: "x\na\nb\ny",
+			to:   "a\nb\nz",
+			want: []DiffLine{
+				{Op: "delete", Text: "x"},
+				{Op: "equal", Text: "a"},
+				{Op: "equal", Text: "b"},
+				{Op: "delete", Text: "y"},
+				{Op: "insert", Text: "z"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("diffLines(%q, %q) = %+v, 期望 %+v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
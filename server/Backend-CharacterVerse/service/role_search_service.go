@@ -0,0 +1,318 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/utils"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm/clause"
+)
+
+// 搜索参数
+const (
+	maxSearchKeywords     = 5  // 单次搜索最多关键词数
+	ngramTokenSize        = 2  // 与MySQL ngram_token_size保持一致，更短的关键词退化为LIKE匹配
+	descriptionSnippetLen = 60 // 描述摘要长度（字符）
+)
+
+// 搜索字段及权重：名称 > 拼音 > 标签 > 创建者 > 描述
+var roleSearchFields = []struct {
+	column string
+	weight int
+}{
+	{"name", 8},
+	{"name_pinyin", 6},
+	{"tag", 4},
+	{"creator", 2},
+	{"description", 1},
+}
+
+// 去除MySQL布尔模式中的运算符，避免用户输入改变查询语义
+var searchOperatorReplacer = strings.NewReplacer(
+	"+", " ", "-", " ", "<", " ", ">", " ", "(", " ", ")", " ",
+	"~", " ", "*", " ", "\"", " ", "@", " ", "%", " ", "_", " ",
+)
+
+// RoleSearchHit 搜索结果，包含相关度和高亮片段
+type RoleSearchHit struct {
+	model.Role
+	CreatorUsername string            `json:"creator_username"`
+	Score           float64           `json:"score"`
+	Highlights      map[string]string `json:"highlights,omitempty"` // 字段 -> 带<em>标记的片段
+}
+
+// 查询结果行
+type roleSearchRow struct {
+	model.Role
+	Creator   string
+	DocTags   string // 搜索文档中的全部标签
+	Relevance float64
+}
+
+// IndexRole 创建或更新角色的搜索文档
+func IndexRole(roleID uint) error {
+	var role model.Role
+	if err := database.DB.First(&role, roleID).Error; err != nil {
+		return err
+	}
+
+	var creator model.User
+	database.DB.Select("username").First(&creator, role.UserID)
+
+	tags, err := getRoleTagNames(role.ID)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		tags = []string{role.Tag}
+	}
+
+	doc := model.RoleSearchDocument{
+		RoleID:      role.ID,
+		Name:        role.Name,
+		Tag:         strings.Join(tags, " "),
+		Creator:     creator.Username,
+		Description: role.Description,
+		NamePinyin:  utils.PinyinInitials(role.Name),
+	}
+	return database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&doc).Error
+}
+
+// RemoveRoleFromIndex 删除角色的搜索文档
+func RemoveRoleFromIndex(roleID uint) error {
+	return database.DB.Delete(&model.RoleSearchDocument{}, roleID).Error
+}
+
+// 更新搜索文档，失败只记录日志，不影响主流程
+func reindexRole(roleID uint) {
+	if err := IndexRole(roleID); err != nil {
+		log.Printf("更新角色搜索索引失败: 角色ID=%d, 错误=%v", roleID, err)
+	}
+}
+
+// BackfillRoleSearchIndex 为尚未建立搜索文档的角色补建索引
+func BackfillRoleSearchIndex() {
+	var roleIDs []uint
+	err := database.DB.Model(&model.Role{}).
+		Joins("LEFT JOIN role_search_documents ON role_search_documents.role_id = roles.id").
+		Where("role_search_documents.role_id IS NULL").
+		Pluck("roles.id", &roleIDs).Error
+	if err != nil {
+		log.Printf("查询待索引角色失败: %v", err)
+		return
+	}
+
+	for _, roleID := range roleIDs {
+		reindexRole(roleID)
+	}
+	if len(roleIDs) > 0 {
+		log.Printf("角色搜索索引补建完成: %d个角色", len(roleIDs))
+	}
+}
+
+// 拆分关键词：去除运算符、转小写、去重
+func parseSearchKeywords(keyword string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.Fields(searchOperatorReplacer.Replace(strings.ToLower(keyword))) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == maxSearchKeywords {
+			break
+		}
+	}
+	return terms
+}
+
+// 生成单个关键词在某字段上的匹配表达式
+func searchFieldMatch(column, term string) (string, interface{}) {
+	if utf8.RuneCountInString(term) < ngramTokenSize {
+		return "d." + column + " LIKE ?", "%" + term + "%"
+	}
+	return "MATCH(d." + column + ") AGAINST (? IN BOOLEAN MODE)", `"` + term + `"`
+}
+
+// SearchRolesByKeyword 按相关度搜索角色
+// 多个关键词以空格分隔，需全部命中；名称支持拼音首字母匹配
+func SearchRolesByKeyword(keyword string, pagination model.Pagination) (*model.PaginatedResult, error) {
+	terms := parseSearchKeywords(keyword)
+	if len(terms) == 0 {
+		return &model.PaginatedResult{List: []RoleSearchHit{}, Page: pagination.Page}, nil
+	}
+
+	query := database.DB.Table("role_search_documents AS d").
+		Joins("JOIN roles ON roles.id = d.role_id AND roles.deleted_at IS NULL").
+		Scopes(visibleRoles(0))
+
+	var scoreParts []string
+	var scoreArgs []interface{}
+	for _, term := range terms {
+		// 每个关键词至少命中一个字段
+		var matchParts []string
+		var matchArgs []interface{}
+		for _, field := range roleSearchFields {
+			expr, arg := searchFieldMatch(field.column, term)
+			matchParts = append(matchParts, expr)
+			matchArgs = append(matchArgs, arg)
+
+			scoreParts = append(scoreParts, "("+expr+") * "+strconv.Itoa(field.weight))
+			scoreArgs = append(scoreArgs, arg)
+		}
+		if utf8.RuneCountInString(term) >= ngramTokenSize {
+			// 使用联合全文索引过滤
+			query = query.Where("MATCH(d.name, d.tag, d.creator, d.description, d.name_pinyin) AGAINST (? IN BOOLEAN MODE)", `"`+term+`"`)
+		} else {
+			query = query.Where("("+strings.Join(matchParts, " OR ")+")", matchArgs...)
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.PageSize
+	totalPages := (int(total) + pagination.PageSize - 1) / pagination.PageSize
+
+	// 未指定排序时按相关度排序
+	order := "relevance DESC, roles.created_at DESC"
+	if pagination.Sort != "" {
+		query = joinRoleRankings(query, pagination.Sort)
+		order = roleOrderClause(pagination.Sort)
+	}
+
+	var rows []roleSearchRow
+	err := query.
+		Select("roles.*, d.creator AS creator, d.tag AS doc_tags, ("+strings.Join(scoreParts, " + ")+") AS relevance", scoreArgs...).
+		Order(order).
+		Offset(offset).
+		Limit(pagination.PageSize).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	roleIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		roleIDs = append(roleIDs, row.ID)
+	}
+	roleTags, err := loadRoleTags(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]RoleSearchHit, 0, len(rows))
+	for _, row := range rows {
+		row.Role.Tags = roleTags[row.ID]
+		hits = append(hits, RoleSearchHit{
+			Role:            row.Role,
+			CreatorUsername: row.Creator,
+			Score:           row.Relevance,
+			Highlights:      buildHighlights(row, terms),
+		})
+	}
+
+	return &model.PaginatedResult{
+		Total:   total,
+		List:    hits,
+		Page:    pagination.Page,
+		Pages:   totalPages,
+		HasMore: pagination.Page < totalPages,
+	}, nil
+}
+
+// 为命中的字段生成高亮片段
+func buildHighlights(row roleSearchRow, terms []string) map[string]string {
+	highlights := make(map[string]string)
+	for field, text := range map[string]string{
+		"name":             row.Name,
+		"tags":             row.DocTags,
+		"creator_username": row.Creator,
+	} {
+		if snippet := highlightText(text, terms, 0); snippet != "" {
+			highlights[field] = snippet
+		}
+	}
+	if snippet := highlightText(row.Description, terms, descriptionSnippetLen); snippet != "" {
+		highlights["description"] = snippet
+	}
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// highlightText 用<em>标记文本中命中的关键词，未命中返回空字符串
+// maxRunes大于0时截取首个命中位置附近的片段
+func highlightText(text string, terms []string, maxRunes int) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记命中的字符
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		termRunes := []rune(term)
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) != term {
+				continue
+			}
+			for j := i; j < i+len(termRunes); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return ""
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		start = first - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var builder strings.Builder
+	if start > 0 {
+		builder.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			builder.WriteString("<em>" + segment + "</em>")
+		} else {
+			builder.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		builder.WriteString("…")
+	}
+	return builder.String()
+}
@@ -3,15 +3,12 @@ package service
 import (
 	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/model"
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"mime/multipart"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -23,7 +20,7 @@ var validGenders = map[string]bool{
 	"男": true, "女": true, "其他": true, "未知": true,
 }
 
-func AddRole(userID uint, name, description, gender string, age int, voiceType, tag string) (uint, error) {
+func AddRole(userID uint, name, description, gender string, age int, voiceType, tag string, tags []string, voiceParams model.VoiceParams, visibility string) (uint, error) {
 	// 参数校验集中处理
 	if name == "" {
 		return 0, errors.New("角色名称不能为空")
@@ -34,8 +31,21 @@ func AddRole(userID uint, name, description, gender string, age int, voiceType,
 	if age < 0 || age > 120 {
 		return 0, errors.New("年龄必须在0-120之间")
 	}
-	if _, valid := model.GetVoiceInfo(voiceType); !valid {
-		return 0, errors.New("无效的声音类型")
+	if err := ValidateVoiceType(voiceType, userID); err != nil {
+		return 0, err
+	}
+	if err := voiceParams.Validate(); err != nil {
+		return 0, err
+	}
+	// 未指定的语音参数使用默认值
+	voiceParams = model.DefaultVoiceParams().Merge(&voiceParams)
+
+	// 未指定可见性时默认公开
+	if visibility == "" {
+		visibility = model.VisibilityPublic
+	}
+	if !model.IsValidVisibility(visibility) {
+		return 0, fmt.Errorf("无效的可见性，有效值为: %v", model.ValidVisibilities)
 	}
 
 	// 验证标签是否有效
@@ -50,6 +60,12 @@ func AddRole(userID uint, name, description, gender string, age int, voiceType,
 		return 0, fmt.Errorf("无效的角色标签，有效标签为: %v", model.ValidRoleTags)
 	}
 
+	// 主分类之外的自定义标签
+	allTags, err := mergeRoleTags(tag, tags)
+	if err != nil {
+		return 0, err
+	}
+
 	// 创建新角色（包含标签）
 	newRole := model.Role{
 		Name:        name,
@@ -59,218 +75,31 @@ func AddRole(userID uint, name, description, gender string, age int, voiceType,
 		Age:         age,
 		VoiceType:   voiceType,
 		Tag:         tag, // 设置标签
-	}
 
-	if err := database.DB.Create(&newRole).Error; err != nil {
-		return 0, err
+		VoiceSpeed:   voiceParams.Speed,
+		VoicePitch:   voiceParams.Pitch,
+		VoiceVolume:  voiceParams.Volume,
+		VoiceEmotion: voiceParams.Emotion,
+		Visibility:   visibility,
 	}
 
-	// 异步生成头像
-	go func(roleID uint, name, description string) {
-		// 生成头像提示词
-		prompt := fmt.Sprintf("角色头像：%s，%s", name, description)
-
-		// 调用阿里云API生成头像（带重试）
-		var imageURL string
-		var err error
-		for attempt := 1; attempt <= 3; attempt++ {
-			log.Printf("尝试生成头像 (第 %d/3 次)", attempt)
-			imageURL, err = generateAvatarWithAliyun(prompt)
-			if err == nil {
-				break
-			}
-			log.Printf("头像生成失败 (第 %d/3 次): %v", attempt, err)
-			time.Sleep(2 * time.Second) // 重试前等待
-		}
-
-		if err != nil {
-			log.Printf("头像生成最终失败: %v", err)
-			return
-		}
-
-		// 下载生成的图片
-		imageData, err := downloadImage(imageURL)
-		if err != nil {
-			log.Printf("图片下载失败: %v", err)
-			return
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newRole).Error; err != nil {
+			return err
 		}
-
-		// 上传到服务器（带重试）
-		var avatarURL string
-		for attempt := 1; attempt <= 3; attempt++ {
-			log.Printf("尝试上传头像 (第 %d/3 次)", attempt)
-			avatarURL, err = uploadImageToServer(imageData, fmt.Sprintf("%s_avatar.png", name))
-			if err == nil {
-				break
-			}
-			log.Printf("图片上传失败 (第 %d/3 次): %v", attempt, err)
-			time.Sleep(2 * time.Second) // 重试前等待
-		}
-
-		if err != nil {
-			log.Printf("图片上传最终失败: %v", err)
-			return
-		}
-
-		// 更新角色头像URL
-		if err := database.DB.Model(&model.Role{}).
-			Where("id = ?", roleID).
-			Update("avatar_url", avatarURL).Error; err != nil {
-			log.Printf("更新头像URL失败: %v", err)
-		} else {
-			log.Printf("角色头像更新成功: %s", avatarURL)
-		}
-	}(newRole.ID, name, description)
-
-	return newRole.ID, nil
-}
-
-// 调用阿里云API生成头像
-func generateAvatarWithAliyun(prompt string) (string, error) {
-	apiKey := os.Getenv("DASHSCOPE_API_KEY")
-	if apiKey == "" {
-		return "", errors.New("阿里云API密钥未配置")
-	}
-
-	// 调用阿里云API - 创建任务
-	taskID, err := createImageTask(apiKey, prompt)
+		return setRoleTags(tx, newRole.ID, allTags)
+	})
 	if err != nil {
-		return "", err
-	}
-
-	// 轮询获取任务结果
-	return pollImageTask(apiKey, taskID)
-}
-
-// 创建图片生成任务
-func createImageTask(apiKey, prompt string) (string, error) {
-	url := "https://dashscope.aliyuncs.com/api/v1/services/aigc/text2image/image-synthesis"
-
-	payload := map[string]interface{}{
-		"model": "wan2.2-t2i-flash", // 使用更快的极速版模型
-		"input": map[string]interface{}{
-			"prompt": prompt,
-		},
-		"parameters": map[string]interface{}{
-			"size":          "1024*1024",
-			"n":             1,
-			"prompt_extend": true, // 开启智能提示词优化
-		},
-	}
-
-	jsonData, _ := json.Marshal(payload)
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-
-	// 添加必要请求头
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-DashScope-Async", "enable") // 必须添加的异步头
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API请求失败: 状态码 %d, 响应: %s", resp.StatusCode, string(body))
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-
-	// 解析任务ID
-	type TaskResponse struct {
-		Output struct {
-			TaskID string `json:"task_id"`
-		} `json:"output"`
-		Code    string `json:"code"`
-		Message string `json:"message"`
-	}
-
-	var result TaskResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return "", fmt.Errorf("解析任务响应失败: %v, 响应体: %s", err, string(body))
-	}
-
-	if result.Code != "" {
-		return "", fmt.Errorf("API错误: %s - %s", result.Code, result.Message)
-	}
-
-	if result.Output.TaskID == "" {
-		return "", errors.New("未获取到任务ID")
+		return 0, err
 	}
+	reindexRole(newRole.ID)
 
-	log.Printf("任务创建成功, ID: %s", result.Output.TaskID)
-	return result.Output.TaskID, nil
-}
-
-// 轮询任务结果
-func pollImageTask(apiKey, taskID string) (string, error) {
-	url := fmt.Sprintf("https://dashscope.aliyuncs.com/api/v1/tasks/%s", taskID)
-
-	// 设置轮询参数
-	maxAttempts := 50        // 最大尝试次数
-	delay := 5 * time.Second // 每次轮询间隔
-
-	for i := 0; i < maxAttempts; i++ {
-		req, _ := http.NewRequest("GET", url, nil)
-		req.Header.Set("Authorization", "Bearer "+apiKey)
-
-		client := &http.Client{Timeout: 30 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return "", err
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return "", fmt.Errorf("任务查询失败: 状态码 %d", resp.StatusCode)
-		}
-
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		// 解析任务状态
-		type TaskStatusResponse struct {
-			Output struct {
-				TaskStatus string `json:"task_status"`
-				Results    []struct {
-					URL string `json:"url"`
-				} `json:"results"`
-			} `json:"output"`
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		}
-
-		var status TaskStatusResponse
-		if err := json.Unmarshal(body, &status); err != nil {
-			// 如果解析失败，打印原始响应体
-			log.Printf("解析任务状态失败: %v, 响应体: %s", err, string(body))
-			return "", fmt.Errorf("解析任务状态失败: %v", err)
-		}
-
-		// 打印任务状态
-		log.Printf("任务状态: %s, 等待中... (尝试 %d/%d)", status.Output.TaskStatus, i+1, maxAttempts)
-
-		switch status.Output.TaskStatus {
-		case "SUCCEEDED":
-			if len(status.Output.Results) > 0 && status.Output.Results[0].URL != "" {
-				log.Printf("任务成功完成, 图片URL: %s", status.Output.Results[0].URL)
-				return status.Output.Results[0].URL, nil
-			}
-			return "", errors.New("任务成功但未获取到图片URL")
-
-		case "FAILED", "CANCELED":
-			return "", fmt.Errorf("任务失败: %s - %s", status.Code, status.Message)
-
-		default: // PENDING, RUNNING
-			time.Sleep(delay)
-		}
+	// 头像生成任务由后台工作池处理
+	if _, err := EnqueueAvatarJob(&newRole, "", 1); err != nil {
+		log.Printf("创建头像生成任务失败: 角色ID=%d, 错误=%v", newRole.ID, err)
 	}
 
-	return "", errors.New("任务超时未完成")
+	return newRole.ID, nil
 }
 
 // 下载图片
@@ -299,73 +128,35 @@ func downloadImage(url string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// 上传图片到服务器
-func uploadImageToServer(imageData []byte, filename string) (string, error) {
-	// 创建表单数据
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-
-	// 添加文件字段
-	part, err := writer.CreateFormFile("file", filename)
-	if err != nil {
-		return "", err
-	}
-
-	if _, err := part.Write(imageData); err != nil {
-		return "", err
-	}
-
-	// 关闭writer
-	if err := writer.Close(); err != nil {
-		return "", err
-	}
-
-	// 创建请求
-	req, err := http.NewRequest("POST", "https://ai.mcell.top/api/upload_voice", body)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	// 发送请求（增加超时时间）
-	client := &http.Client{
-		Timeout: 120 * time.Second, // 更长的超时时间
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态码
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("上传失败: 状态码 %d, 响应: %s", resp.StatusCode, string(body))
-	}
-
-	// 解析响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	type UploadResponse struct {
-		Code     int    `json:"code"`
-		Message  string `json:"message"`
-		Filename string `json:"filename"`
-		URL      string `json:"url"`
-	}
+// 保存图片到对象存储，返回对象键
+func uploadImage(ctx context.Context, kind string, ownerID uint, key string, imageData []byte) (string, error) {
+	return storeObject(ctx, kind, ownerID, key, imageData, http.DetectContentType(imageData))
+}
 
-	var result UploadResponse
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		return "", fmt.Errorf("解析上传响应失败: %v, 响应体: %s", err, string(respBody))
+// 排序方式对应的排序子句，未知排序方式按最新创建排序
+func roleOrderClause(sort string) string {
+	switch sort {
+	case model.SortHot:
+		return "COALESCE(role_rankings.hot_score, 0) DESC, roles.created_at DESC"
+	case model.SortTrending:
+		return "COALESCE(role_rankings.trending_score, 0) DESC, roles.created_at DESC"
+	case model.SortPopular, model.SortTop:
+		return "roles.like_count + roles.favorite_count DESC, roles.created_at DESC"
+	case model.SortLikes:
+		return "roles.like_count DESC, roles.created_at DESC"
+	case model.SortFavorites:
+		return "roles.favorite_count DESC, roles.created_at DESC"
+	default:
+		return "roles.created_at DESC"
 	}
+}
 
-	if result.Code != 0 {
-		return "", fmt.Errorf("上传失败: %s (代码 %d)", result.Message, result.Code)
+// 热度排序需要关联排行表，没有排行记录的角色得分视为0
+func joinRoleRankings(query *gorm.DB, sort string) *gorm.DB {
+	if sort == model.SortHot || sort == model.SortTrending {
+		return query.Joins("LEFT JOIN role_rankings ON role_rankings.role_id = roles.id")
 	}
-
-	return fmt.Sprintf("https://ai.mcell.top%s", result.URL), nil
+	return query
 }
 
 // 通用分页查询逻辑
@@ -384,8 +175,9 @@ func paginateRoles(query *gorm.DB, pagination model.Pagination) (*model.Paginate
 	hasMore := pagination.Page < totalPages
 
 	// 获取数据
-	if err := query.
-		Order("created_at DESC").
+	if err := joinRoleRankings(query, pagination.Sort).
+		Preload("Tags").
+		Order(roleOrderClause(pagination.Sort)).
 		Offset(offset).
 		Limit(pagination.PageSize).
 		Find(&roles).Error; err != nil {
@@ -401,45 +193,50 @@ func paginateRoles(query *gorm.DB, pagination model.Pagination) (*model.Paginate
 	}, nil
 }
 
-func GetRoles(pagination model.Pagination) (*model.PaginatedResult, error) {
-	return paginateRoles(database.DB, pagination)
+// visibleRoles 只保留对查看者可见的角色：公开角色及查看者自己创建的角色
+// viewerID 为 0 表示未登录
+func visibleRoles(viewerID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return db.Where("roles.visibility = ?", model.VisibilityPublic)
+		}
+		return db.Where("(roles.visibility = ? OR roles.user_id = ?)", model.VisibilityPublic, viewerID)
+	}
 }
 
-// 通过用户名模糊查询角色
-func GetRolesByUsername(username string, pagination model.Pagination) (*model.PaginatedResult, error) {
-	// 关联用户表进行模糊查询
-	query := database.DB.
-		Joins("JOIN users ON users.id = roles.user_id").
-		Where("users.username LIKE ?", "%"+username+"%")
+// GetAccessibleRole 获取用户可以访问的角色（私有角色仅创建者可访问）
+func GetAccessibleRole(roleID, userID uint) (*model.Role, error) {
+	role, err := database.GetRoleByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.CanBeAccessedBy(userID) {
+		return nil, database.ErrRoleNotFound
+	}
+	return role, nil
+}
 
-	return paginateRoles(query, pagination)
+func GetRoles(viewerID uint, filter model.RoleFilter, pagination model.Pagination) (*model.PaginatedResult, error) {
+	return paginateRoles(database.DB.Scopes(visibleRoles(viewerID), roleTagFilter(filter)), pagination)
 }
 
-// 通过标签模糊查询角色
-func GetRolesByTag(tag string, pagination model.Pagination) (*model.PaginatedResult, error) {
-	// 使用LIKE进行模糊查询
+// 通过用户名模糊查询角色
+func GetRolesByUsername(username string, viewerID uint, pagination model.Pagination) (*model.PaginatedResult, error) {
+	// 关联用户表进行模糊查询
 	query := database.DB.
-		Where("tag LIKE ?", "%"+tag+"%")
+		Joins("JOIN users ON users.id = roles.user_id").
+		Where("users.username LIKE ?", "%"+username+"%").
+		Scopes(visibleRoles(viewerID))
 
 	return paginateRoles(query, pagination)
 }
 
-// 通过关键字模糊查询角色（按照角色名称、用户名、标签、描述的顺序）
-func SearchRolesByKeyword(keyword string, pagination model.Pagination) (*model.PaginatedResult, error) {
-	// 构建基础查询
-	baseQuery := database.DB.Model(&model.Role{})
-
-	// 使用OR条件组合多个字段的模糊查询
-	query := baseQuery.Where(
-		"name LIKE ? OR "+
-			"id IN (SELECT roles.id FROM roles JOIN users ON users.id = roles.user_id WHERE users.username LIKE ?) OR "+
-			"tag LIKE ? OR "+
-			"description LIKE ?",
-		"%"+keyword+"%",
-		"%"+keyword+"%",
-		"%"+keyword+"%",
-		"%"+keyword+"%",
-	)
+// 按标签查询角色，支持多标签 AND/OR 过滤
+func GetRolesByTag(filter model.RoleFilter, pagination model.Pagination) (*model.PaginatedResult, error) {
+	if len(filter.Tags) == 0 {
+		return nil, errors.New("必须提供标签")
+	}
+	query := database.DB.Scopes(visibleRoles(0), roleTagFilter(filter))
 
 	return paginateRoles(query, pagination)
 }
@@ -460,6 +257,12 @@ func DeleteRole(roleID, userID uint) error {
 	if err := database.DB.Delete(&role).Error; err != nil {
 		return err
 	}
+	if err := RemoveRoleFromIndex(roleID); err != nil {
+		log.Printf("删除角色搜索索引失败: 角色ID=%d, 错误=%v", roleID, err)
+	}
+	if err := refreshRoleTagUsage(database.DB, roleID); err != nil {
+		log.Printf("刷新标签使用次数失败: 角色ID=%d, 错误=%v", roleID, err)
+	}
 
 	return nil
 }
@@ -484,6 +287,13 @@ func UpdateRole(roleID, userID uint, updates map[string]interface{}) error {
 		"age":         true,
 		"voice_type":  true,
 		"tag":         true, // 新增标签字段
+
+		"voice_speed":   true,
+		"voice_pitch":   true,
+		"voice_volume":  true,
+		"voice_emotion": true,
+		"visibility":    true,
+		"allow_fork":    true,
 	}
 
 	// 过滤无效字段
@@ -512,16 +322,58 @@ func UpdateRole(roleID, userID uint, updates map[string]interface{}) error {
 
 	// 验证声音类型
 	if voiceType, ok := cleanUpdates["voice_type"]; ok {
-		if _, valid := model.GetVoiceInfo(voiceType.(string)); !valid {
+		voiceTypeStr, isString := voiceType.(string)
+		if !isString {
 			return errors.New("无效的声音类型")
 		}
+		if err := ValidateVoiceType(voiceTypeStr, userID); err != nil {
+			return err
+		}
+	}
+
+	// 验证语音参数
+	for field, name := range map[string]string{"voice_speed": "语速", "voice_pitch": "音调", "voice_volume": "音量"} {
+		if value, ok := cleanUpdates[field]; ok {
+			ratio, isNumber := value.(float64)
+			if !isNumber {
+				return fmt.Errorf("%s必须为数字", name)
+			}
+			if ratio == 0 {
+				ratio = 1.0
+			}
+			if err := model.ValidateVoiceRatio(name, ratio); err != nil {
+				return err
+			}
+			cleanUpdates[field] = ratio
+		}
+	}
+	if emotion, ok := cleanUpdates["voice_emotion"]; ok {
+		emotionStr, isString := emotion.(string)
+		if !isString || !model.IsValidVoiceEmotion(emotionStr) {
+			return fmt.Errorf("无效的语音情感，有效值为: %v", model.ValidVoiceEmotions[1:])
+		}
+	}
+
+	// 验证可见性
+	if visibility, ok := cleanUpdates["visibility"]; ok {
+		visibilityStr, isString := visibility.(string)
+		if !isString || !model.IsValidVisibility(visibilityStr) {
+			return fmt.Errorf("无效的可见性，有效值为: %v", model.ValidVisibilities)
+		}
+	}
+
+	// 验证是否允许派生
+	if allowFork, ok := cleanUpdates["allow_fork"]; ok {
+		if _, isBool := allowFork.(bool); !isBool {
+			return errors.New("allow_fork必须为布尔值")
+		}
 	}
 
 	// 验证标签（如果更新）
 	if tag, ok := cleanUpdates["tag"]; ok {
 		validTag := false
 		for _, t := range model.ValidRoleTags {
-			if t == tag.(string) {
+			if t == tag {
 				validTag = true
 				break
 			}
@@ -531,10 +383,59 @@ func UpdateRole(roleID, userID uint, updates map[string]interface{}) error {
 		}
 	}
 
-	// 执行更新
-	if err := database.DB.Model(&role).Updates(cleanUpdates).Error; err != nil {
+	// 主分类或自定义标签变化时重建标签关联
+	var newTags []string
+	rawTags, tagsChanged := updates["tags"]
+	newCategory, categoryChanged := cleanUpdates["tag"].(string)
+	if tagsChanged || categoryChanged {
+		var extra []string
+		if tagsChanged {
+			list, isList := rawTags.([]interface{})
+			if !isList {
+				return errors.New("tags必须为字符串数组")
+			}
+			for _, item := range list {
+				name, isString := item.(string)
+				if !isString {
+					return errors.New("tags必须为字符串数组")
+				}
+				extra = append(extra, name)
+			}
+		} else {
+			// 仅修改主分类时保留原有的其他标签
+			existing, err := getRoleTagNames(roleID)
+			if err != nil {
+				return err
+			}
+			for _, name := range existing {
+				if name != role.Tag {
+					extra = append(extra, name)
+				}
+			}
+		}
+
+		category := role.Tag
+		if categoryChanged {
+			category = newCategory
+		}
+		var err error
+		if newTags, err = mergeRoleTags(category, extra); err != nil {
+			return err
+		}
+	}
+
+	if len(cleanUpdates) == 0 && newTags == nil {
+		return nil
+	}
+
+	// 保存修改前的版本快照后执行更新
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return applyRoleRevision(tx, &role, userID, cleanUpdates, newTags)
+	})
+	if err != nil {
 		return err
 	}
+	reindexRole(roleID)
 
 	return nil
 }
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/storage"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 使用内存SQLite替换全局数据库，并迁移用到的模型
+func setupTestDB(t *testing.T, models ...interface{}) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移测试数据库失败: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// 使用 miniredis 替换全局Redis客户端
+func setupTestRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	server := miniredis.RunT(t)
+
+	previous := database.RedisClient
+	database.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		database.RedisClient.Close()
+		database.RedisClient = previous
+	})
+	return server
+}
+
+// 使用临时目录中的本地存储
+func setupTestStorage(t *testing.T) {
+	t.Helper()
+	t.Setenv("BLOB_STORE", storage.BackendLocal)
+	t.Setenv("BLOB_LOCAL_DIR", t.TempDir())
+	t.Setenv("UPLOAD_TOKEN_SECRET", "test-secret")
+	if err := storage.Init(config.LoadConfig()); err != nil {
+		t.Fatalf("初始化测试存储失败: %v", err)
+	}
+}
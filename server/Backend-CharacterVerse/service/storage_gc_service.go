@@ -0,0 +1,350 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 分布式锁，避免多实例同时回收
+const storageGCLockKey = "storage:gc:lock"
+
+// 批量读写的记录数
+const storageGCBatchSize = 500
+
+// OrphanedObject 待回收的文件
+type OrphanedObject struct {
+	Key               string    `json:"key"`
+	Kind              string    `json:"kind"`
+	OwnerID           uint      `json:"owner_id"`
+	Size              int64     `json:"size"`
+	UnreferencedSince time.Time `json:"unreferenced_since"`
+}
+
+// StorageGCReport 回收结果（试运行时为将要执行的操作）
+type StorageGCReport struct {
+	DryRun      bool             `json:"dry_run"`
+	GracePeriod string           `json:"grace_period"`
+	Tracked     int              `json:"tracked"`    // 已追踪的文件数
+	Referenced  int              `json:"referenced"` // 仍被引用的文件数
+	Pending     int              `json:"pending"`    // 无引用但仍在宽限期内的文件数
+	Expired     []OrphanedObject `json:"expired"`    // 超过宽限期、将被删除的文件
+	ExpiredSize int64            `json:"expired_size"`
+	Deleted     int              `json:"deleted"`
+	Failed      int              `json:"failed"`
+}
+
+// 保存文件到对象存储并登记，供回收任务追踪引用
+// 用户上传的文件需通过 storage.WithUploader 指定上传者，其余按系统身份保存
+func storeObject(ctx context.Context, kind string, ownerID uint, key string, data []byte, contentType string) (string, error) {
+	key, err := storage.Put(ctx, key, data, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	// 内容相同的文件可能复用同一对象键，重新登记时清除无引用标记
+	object := model.StoredObject{
+		Key:        key,
+		Kind:       kind,
+		OwnerID:    ownerID,
+		UploaderID: storage.UploaderFromContext(ctx),
+		Size:       int64(len(data)),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"unreferenced_since": nil}),
+	}).Create(&object).Error; err != nil {
+		log.Printf("登记存储对象失败: 对象键=%s, 错误=%v", key, err)
+	}
+	return key, nil
+}
+
+// 复用已保存的对象：先清除无引用标记，再确认对象未被回收
+// 回收任务只删除带无引用标记的记录，清除后不会再被本轮回收
+func reuseStoredObject(key string) bool {
+	if err := database.DB.Model(&model.StoredObject{}).
+		Where("`key` = ? AND unreferenced_since IS NOT NULL", key).
+		Update("unreferenced_since", nil).Error; err != nil {
+		log.Printf("清除无引用标记失败: 对象键=%s, 错误=%v", key, err)
+		return false
+	}
+	var count int64
+	if err := database.DB.Model(&model.StoredObject{}).Where("`key` = ?", key).Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// StartStorageGC 启动无引用文件回收任务
+func StartStorageGC() {
+	interval := time.Duration(config.LoadConfig().StorageGCIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		log.Println("存储回收任务已禁用")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+			runStorageGC(interval)
+		}
+	}()
+}
+
+// 获取锁后执行一次回收
+func runStorageGC(interval time.Duration) {
+	ctx := context.Background()
+	acquired, err := database.RedisClient.SetNX(ctx, storageGCLockKey, time.Now().Unix(), interval*9/10).Result()
+	if err != nil {
+		log.Printf("获取存储回收任务锁失败: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	report, err := SweepStoredObjects(false)
+	if err != nil {
+		log.Printf("存储回收失败: %v", err)
+		return
+	}
+	log.Printf("存储回收完成: 追踪=%d, 引用中=%d, 宽限期内=%d, 删除=%d, 失败=%d",
+		report.Tracked, report.Referenced, report.Pending, report.Deleted, report.Failed)
+}
+
+// SweepStoredObjects 扫描引用，标记无引用的文件，删除超过宽限期的文件
+// dryRun 为 true 时只生成报告，不修改任何数据
+func SweepStoredObjects(dryRun bool) (*StorageGCReport, error) {
+	grace := time.Duration(config.LoadConfig().StorageGCGraceHours) * time.Hour
+	now := time.Now()
+	report := &StorageGCReport{DryRun: dryRun, GracePeriod: grace.String(), Expired: []OrphanedObject{}}
+
+	referenced, err := collectReferencedKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	// 按主键分批扫描已追踪的文件，避免一次载入整张表
+	var objects []model.StoredObject
+	err = database.DB.FindInBatches(&objects, storageGCBatchSize, func(tx *gorm.DB, batch int) error {
+		return sweepStoredObjectBatch(objects, referenced, report, now, grace)
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// 处理一批已追踪的文件：更新无引用标记并删除超过宽限期的文件
+func sweepStoredObjectBatch(objects []model.StoredObject, referenced map[string]bool, report *StorageGCReport, now time.Time, grace time.Duration) error {
+	report.Tracked += len(objects)
+
+	var newlyReferenced, newlyUnreferenced []uint
+	var expired []model.StoredObject
+	for _, object := range objects {
+		switch {
+		case referenced[object.Key]:
+			report.Referenced++
+			if object.UnreferencedSince != nil {
+				newlyReferenced = append(newlyReferenced, object.ID)
+			}
+		case object.UnreferencedSince == nil:
+			// 首次发现无引用，从此时开始计算宽限期
+			report.Pending++
+			newlyUnreferenced = append(newlyUnreferenced, object.ID)
+		case now.Sub(*object.UnreferencedSince) < grace:
+			report.Pending++
+		default:
+			expired = append(expired, object)
+			report.Expired = append(report.Expired, OrphanedObject{
+				Key:               object.Key,
+				Kind:              object.Kind,
+				OwnerID:           object.OwnerID,
+				Size:              object.Size,
+				UnreferencedSince: *object.UnreferencedSince,
+			})
+			report.ExpiredSize += object.Size
+		}
+	}
+	if report.DryRun {
+		return nil
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if len(newlyReferenced) > 0 {
+			if err := tx.Model(&model.StoredObject{}).Where("id IN ?", newlyReferenced).
+				Update("unreferenced_since", nil).Error; err != nil {
+				return err
+			}
+		}
+		if len(newlyUnreferenced) > 0 {
+			if err := tx.Model(&model.StoredObject{}).Where("id IN ?", newlyUnreferenced).
+				Update("unreferenced_since", now).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, object := range expired {
+		deleted, err := deleteStoredObject(object)
+		if err != nil {
+			log.Printf("删除无引用文件失败: 对象键=%s, 错误=%v", object.Key, err)
+			report.Failed++
+			continue
+		}
+		if deleted {
+			report.Deleted++
+		}
+	}
+	return nil
+}
+
+// 删除文件，删除前在事务中再次确认仍无引用（扫描期间可能被重新登记或引用），返回是否已删除
+func deleteStoredObject(object model.StoredObject) (bool, error) {
+	deleted := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND unreferenced_since IS NOT NULL", object.ID).Delete(&model.StoredObject{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		referenced, err := hasObjectRefs(tx, object.Key)
+		if err != nil {
+			return err
+		}
+		if referenced {
+			// 回滚删除，下次扫描时清除无引用标记
+			return errObjectReferenced
+		}
+		deleted = true
+		return nil
+	})
+	if errors.Is(err, errObjectReferenced) {
+		return false, nil
+	}
+	if err != nil || !deleted {
+		return false, err
+	}
+
+	// 上传服务按上传者记录引用，需以上传时的身份删除
+	ctx := storage.WithUploader(context.Background(), object.UploaderID)
+	if err := storage.Delete(ctx, object.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		// 删除失败时恢复登记，下次继续回收
+		database.DB.Create(&object)
+		return false, err
+	}
+	return true, nil
+}
+
+// 对象在扫描后被重新引用，放弃删除
+var errObjectReferenced = errors.New("对象仍被引用")
+
+// 检查对象键是否仍被引用，与 collectReferencedKeys 扫描的字段一致
+// 旧数据可能保存完整URL、缩略图保存在JSON中，按包含对象键匹配
+func hasObjectRefs(tx *gorm.DB, key string) (bool, error) {
+	pattern := "%" + escapeLike(key) + "%"
+	checks := []*gorm.DB{
+		tx.Model(&model.Role{}).Where("avatar_url LIKE ? OR avatar_thumbnails LIKE ?", pattern, pattern),
+		tx.Model(&model.AvatarCandidate{}).
+			Where("url LIKE ?", pattern).
+			Where("role_id IN (?)", tx.Model(&model.Role{}).Select("id")),
+		tx.Model(&model.ChatHistory{}).Where("voice_url LIKE ?", pattern),
+		tx.Model(&model.Voice{}).Where("url LIKE ?", pattern),
+	}
+	for _, check := range checks {
+		var count int64
+		if err := check.Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// 扫描所有引用文件的记录，返回其中属于本存储的对象键
+// 新增引用文件的字段时需同步修改 hasObjectRefs
+func collectReferencedKeys() (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	add := func(ref string) {
+		if key, err := storage.OwnedKey(ref); err == nil {
+			referenced[key] = true
+		}
+	}
+
+	// 角色头像及缩略图
+	var roles []model.Role
+	err := database.DB.Select("id", "avatar_url", "avatar_thumbnails").
+		Where("avatar_url <> ''").
+		FindInBatches(&roles, storageGCBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, role := range roles {
+				add(string(role.AvatarURL))
+				for _, thumbnail := range role.AvatarThumbnails {
+					add(string(thumbnail))
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 头像候选图（角色删除后不再引用）
+	var candidates []model.AvatarCandidate
+	err = database.DB.Select("id", "url").
+		Where("role_id IN (?)", database.DB.Model(&model.Role{}).Select("id")).
+		FindInBatches(&candidates, storageGCBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, candidate := range candidates {
+				add(string(candidate.URL))
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 聊天记录中的语音（用户发送的语音消息和AI语音回复）
+	var histories []model.ChatHistory
+	err = database.DB.Select("id", "voice_url").
+		Where("voice_url <> ''").
+		FindInBatches(&histories, storageGCBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, history := range histories {
+				add(string(history.VoiceURL))
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 声音试听音频
+	var voices []model.Voice
+	err = database.DB.Select("id", "url").
+		Where("url <> ''").
+		FindInBatches(&voices, storageGCBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, voice := range voices {
+				add(string(voice.URL))
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return referenced, nil
+}
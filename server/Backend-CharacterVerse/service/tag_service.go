@@ -0,0 +1,220 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+	"gorm.io/gorm"
+)
+
+// 标签限制
+const (
+	maxRoleTags      = 10 // 每个角色最多标签数（含主分类）
+	maxTagLength     = 20 // 单个标签最大字符数
+	maxTagFilterSize = 5  // 列表过滤时最多标签数
+)
+
+// NormalizeTag 规范化标签：全角转半角、英文转小写、去除#前缀、合并空白
+func NormalizeTag(raw string) (string, error) {
+	tag := strings.ToLower(width.Fold.String(raw))
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	tag = strings.Join(strings.Fields(tag), " ")
+
+	if tag == "" {
+		return "", errors.New("标签不能为空")
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("标签不能超过%d个字符", maxTagLength)
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_·", r) {
+			return "", fmt.Errorf("标签包含无效字符: %s", raw)
+		}
+	}
+	return tag, nil
+}
+
+// NormalizeTags 规范化并去重一组标签，保持原有顺序
+func NormalizeTags(raw []string) ([]string, error) {
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(raw))
+	for _, r := range raw {
+		tag, err := NormalizeTag(r)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// 合并主分类和自定义标签，主分类排在首位
+func mergeRoleTags(category string, extra []string) ([]string, error) {
+	tags, err := NormalizeTags(append([]string{category}, extra...))
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > maxRoleTags {
+		return nil, fmt.Errorf("每个角色最多%d个标签", maxRoleTags)
+	}
+	return tags, nil
+}
+
+// setRoleTags 替换角色的全部标签，并刷新相关标签的使用次数
+func setRoleTags(tx *gorm.DB, roleID uint, names []string) error {
+	var oldTagIDs []uint
+	if err := tx.Model(&model.RoleTag{}).Where("role_id = ?", roleID).Pluck("tag_id", &oldTagIDs).Error; err != nil {
+		return err
+	}
+
+	newTagIDs := make([]uint, 0, len(names))
+	for _, name := range names {
+		tag := model.Tag{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+		newTagIDs = append(newTagIDs, tag.ID)
+	}
+
+	if err := tx.Where("role_id = ?", roleID).Delete(&model.RoleTag{}).Error; err != nil {
+		return err
+	}
+	if len(newTagIDs) > 0 {
+		roleTags := make([]model.RoleTag, 0, len(newTagIDs))
+		for _, tagID := range newTagIDs {
+			roleTags = append(roleTags, model.RoleTag{RoleID: roleID, TagID: tagID})
+		}
+		if err := tx.Create(&roleTags).Error; err != nil {
+			return err
+		}
+	}
+
+	return database.RefreshTagUsage(tx, append(oldTagIDs, newTagIDs...))
+}
+
+// refreshRoleTagUsage 角色删除或可见性变化后刷新其标签的使用次数
+func refreshRoleTagUsage(tx *gorm.DB, roleID uint) error {
+	var tagIDs []uint
+	if err := tx.Model(&model.RoleTag{}).Where("role_id = ?", roleID).Pluck("tag_id", &tagIDs).Error; err != nil {
+		return err
+	}
+	return database.RefreshTagUsage(tx, tagIDs)
+}
+
+// getRoleTagNames 获取角色的全部标签名
+func getRoleTagNames(roleID uint) ([]string, error) {
+	var names []string
+	err := database.DB.Model(&model.RoleTag{}).
+		Joins("JOIN tags ON tags.id = role_tags.tag_id").
+		Where("role_tags.role_id = ?", roleID).
+		Order("role_tags.created_at, tags.id").
+		Pluck("tags.name", &names).Error
+	return names, err
+}
+
+// loadRoleTags 批量获取角色的标签
+func loadRoleTags(roleIDs []uint) (map[uint][]model.Tag, error) {
+	result := make(map[uint][]model.Tag, len(roleIDs))
+	if len(roleIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []struct {
+		RoleID uint
+		model.Tag
+	}
+	err := database.DB.Table("role_tags").
+		Select("role_tags.role_id, tags.*").
+		Joins("JOIN tags ON tags.id = role_tags.tag_id").
+		Where("role_tags.role_id IN ?", roleIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.RoleID] = append(result[row.RoleID], row.Tag)
+	}
+	return result, nil
+}
+
+// ParseRoleFilter 解析标签过滤条件
+func ParseRoleFilter(rawTags []string, mode string) (model.RoleFilter, error) {
+	filter := model.RoleFilter{TagMode: model.TagModeAny}
+	if mode != "" {
+		if mode != model.TagModeAny && mode != model.TagModeAll {
+			return filter, errors.New("无效的标签过滤模式，有效值为: or, and")
+		}
+		filter.TagMode = mode
+	}
+
+	var nonEmpty []string
+	for _, tag := range rawTags {
+		if strings.TrimSpace(tag) != "" {
+			nonEmpty = append(nonEmpty, tag)
+		}
+	}
+	tags, err := NormalizeTags(nonEmpty)
+	if err != nil {
+		return filter, err
+	}
+	if len(tags) > maxTagFilterSize {
+		return filter, fmt.Errorf("最多同时按%d个标签过滤", maxTagFilterSize)
+	}
+	filter.Tags = tags
+	return filter, nil
+}
+
+// roleTagFilter 按标签过滤角色：or 命中任一标签，and 命中全部标签
+func roleTagFilter(filter model.RoleFilter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(filter.Tags) == 0 {
+			return db
+		}
+		subQuery := database.DB.Table("role_tags").
+			Select("role_tags.role_id").
+			Joins("JOIN tags ON tags.id = role_tags.tag_id").
+			Where("tags.name IN ?", filter.Tags)
+		if filter.TagMode == model.TagModeAll {
+			subQuery = subQuery.Group("role_tags.role_id").
+				Having("COUNT(DISTINCT role_tags.tag_id) = ?", len(filter.Tags))
+		}
+		return db.Where("roles.id IN (?)", subQuery)
+	}
+}
+
+// SearchTags 标签自动补全：按前缀匹配，官方标签优先，其次按使用次数排序
+func SearchTags(prefix string, officialOnly bool, limit int) ([]model.Tag, error) {
+	query := database.DB.Model(&model.Tag{})
+	if prefix != "" {
+		normalized, err := NormalizeTag(prefix)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("name LIKE ?", escapeLike(normalized)+"%")
+	}
+	if officialOnly {
+		query = query.Where("official = ?", true)
+	} else {
+		// 未被使用的自定义标签不出现在补全中
+		query = query.Where("official = ? OR usage_count > 0", true)
+	}
+
+	var tags []model.Tag
+	err := query.Order("official DESC, usage_count DESC, name").Limit(limit).Find(&tags).Error
+	return tags, err
+}
+
+// 转义LIKE通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/model"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TTSCacheStats TTS缓存命中统计
+type TTSCacheStats struct {
+	Enabled   bool    `json:"enabled"`
+	Entries   int     `json:"entries"`
+	SizeBytes int64   `json:"size_bytes"`
+	MaxBytes  int64   `json:"max_bytes"`
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Evictions int64   `json:"evictions"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// 缓存条目
+type ttsCacheEntry struct {
+	key  string
+	size int64
+}
+
+// TTSCache 基于磁盘的内容寻址音频缓存，按总大小做LRU淘汰
+type TTSCache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // 队首为最近使用
+	size    int64
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	evictions atomic.Int64
+}
+
+var (
+	ttsCacheOnce     sync.Once
+	defaultTTSCache  *TTSCache
+	whitespaceFolder = strings.NewReplacer("\r", " ", "\n", " ", "\t", " ", "　", " ")
+)
+
+// getTTSCache 获取全局TTS缓存，未配置目录时返回nil
+func getTTSCache() *TTSCache {
+	ttsCacheOnce.Do(func() {
+		cfg := config.LoadConfig()
+		if cfg.TTSCacheDir == "" || cfg.TTSCacheMaxMB <= 0 {
+			return
+		}
+		cache, err := NewTTSCache(cfg.TTSCacheDir, int64(cfg.TTSCacheMaxMB)<<20)
+		if err != nil {
+			log.Printf("TTS缓存初始化失败，已禁用: %v", err)
+			return
+		}
+		defaultTTSCache = cache
+	})
+	return defaultTTSCache
+}
+
+// NewTTSCache 创建缓存并从磁盘恢复已有条目
+func NewTTSCache(dir string, maxBytes int64) (*TTSCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cache := &TTSCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+
+	// 按修改时间恢复LRU顺序
+	type diskFile struct {
+		key  string
+		size int64
+		mod  int64
+	}
+	var files []diskFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		// 跳过未完成的临时文件等非缓存文件
+		if len(info.Name()) != sha256.Size*2 {
+			return nil
+		}
+		files = append(files, diskFile{key: info.Name(), size: info.Size(), mod: info.ModTime().UnixNano()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod > files[j].mod })
+	for _, f := range files {
+		cache.entries[f.key] = cache.lru.PushBack(&ttsCacheEntry{key: f.key, size: f.size})
+		cache.size += f.size
+	}
+
+	cache.mu.Lock()
+	cache.evictLocked()
+	cache.mu.Unlock()
+
+	log.Printf("TTS缓存已加载: 目录=%s, 条目数=%d, 大小=%d字节", dir, len(cache.entries), cache.size)
+	return cache, nil
+}
+
+// normalizeTTSText 归一化文本：统一空白字符并去除首尾空白
+func normalizeTTSText(text string) string {
+	return strings.Join(strings.Fields(whitespaceFolder.Replace(text)), " ")
+}
+
+// TTSCacheKey 根据音色、语音参数、编码和归一化文本计算缓存键
+func TTSCacheKey(voiceType string, params model.VoiceParams, encoding, text string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%.2f|%.2f|%.2f|%s|%s|%s",
+		voiceType, params.Speed, params.Pitch, params.Volume, params.Emotion, encoding, normalizeTTSText(text))))
+	return hex.EncodeToString(sum[:])
+}
+
+// 缓存文件路径，按键前两位分目录
+func (c *TTSCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+// Get 读取缓存音频
+func (c *TTSCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		// 文件被外部删除，移除索引；解锁期间条目可能已被淘汰并重新写入，只移除原条目
+		c.mu.Lock()
+		if c.entries[key] == elem {
+			c.removeLocked(elem)
+		}
+		c.mu.Unlock()
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.hits.Add(1)
+	return data, true
+}
+
+// Put 写入缓存音频，超出容量时淘汰最久未使用的条目
+func (c *TTSCache) Put(key string, data []byte) {
+	size := int64(len(data))
+	if size == 0 || size > c.maxBytes {
+		return
+	}
+
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("创建TTS缓存目录失败: %v", err)
+		return
+	}
+	// 先写唯一命名的临时文件再重命名，避免读到半个文件或并发写入互相覆盖
+	if err := writeCacheFile(path, data); err != nil {
+		log.Printf("写入TTS缓存失败: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*ttsCacheEntry)
+		c.size += size - entry.size
+		entry.size = size
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[key] = c.lru.PushFront(&ttsCacheEntry{key: key, size: size})
+		c.size += size
+	}
+	c.evictLocked()
+}
+
+// 写入临时文件后原子替换目标文件
+func writeCacheFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// 淘汰条目直到总大小不超过上限，调用方需持有锁
+func (c *TTSCache) evictLocked() {
+	for c.size > c.maxBytes {
+		elem := c.lru.Back()
+		if elem == nil {
+			return
+		}
+		os.Remove(c.path(elem.Value.(*ttsCacheEntry).key))
+		c.removeLocked(elem)
+		c.evictions.Add(1)
+	}
+}
+
+// 从索引中移除条目，调用方需持有锁
+func (c *TTSCache) removeLocked(elem *list.Element) {
+	entry := elem.Value.(*ttsCacheEntry)
+	if _, ok := c.entries[entry.key]; !ok {
+		return
+	}
+	delete(c.entries, entry.key)
+	c.lru.Remove(elem)
+	c.size -= entry.size
+}
+
+// Stats 获取缓存统计
+func (c *TTSCache) Stats() TTSCacheStats {
+	c.mu.Lock()
+	stats := TTSCacheStats{
+		Enabled:   true,
+		Entries:   len(c.entries),
+		SizeBytes: c.size,
+		MaxBytes:  c.maxBytes,
+	}
+	c.mu.Unlock()
+
+	stats.Hits = c.hits.Load()
+	stats.Misses = c.misses.Load()
+	stats.Evictions = c.evictions.Load()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// GetTTSCacheStats 获取全局TTS缓存统计
+func GetTTSCacheStats() TTSCacheStats {
+	cache := getTTSCache()
+	if cache == nil {
+		return TTSCacheStats{}
+	}
+	return cache.Stats()
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"Backend-CharacterVerse/model"
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// 生成测试用缓存键
+func testCacheKey(i int) string {
+	return TTSCacheKey("zh_female", model.VoiceParams{Speed: 1, Pitch: 1, Volume: 1}, "mp3", fmt.Sprintf("文本%d", i))
+}
+
+func TestTTSCacheKeyNormalizesText(t *testing.T) {
+	params := model.VoiceParams{Speed: 1, Pitch: 1, Volume: 1}
+	tests := []struct {
+		name  string
+		a, b  string
+		equal bool
+	}{
+		{"首尾空白", " 你好 ", "你好", true},
+		{"换行与全角空格", "你好\n\t世界", "你好　世界", true},
+		{"内容不同", "你好", "您好", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TTSCacheKey("v", params, "mp3", tt.a) == TTSCacheKey("v", params, "mp3", tt.b)
+			if got != tt.equal {
+				t.Fatalf("键相等 = %v, 期望 %v", got, tt.equal)
+			}
+		})
+	}
+
+	if TTSCacheKey("v", params, "mp3", "你好") == TTSCacheKey("v", params, "wav", "你好") {
+		t.Fatal("不同编码应得到不同的键")
+	}
+	if TTSCacheKey("v", params, "mp3", "你好") == TTSCacheKey("v", model.VoiceParams{Speed: 1.2, Pitch: 1, Volume: 1}, "mp3", "你好") {
+		t.Fatal("不同语速应得到不同的键")
+	}
+}
+
+func TestTTSCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewTTSCache(t.TempDir(), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("a"), 10)
+	for i := 0; i < 3; i++ {
+		cache.Put(testCacheKey(i), data)
+	}
+	// 访问第0条使其成为最近使用，写入第3条时应淘汰第1条
+	if _, ok := cache.Get(testCacheKey(0)); !ok {
+		t.Fatal("第0条应命中")
+	}
+	cache.Put(testCacheKey(3), data)
+
+	tests := []struct {
+		index int
+		hit   bool
+	}{
+		{0, true},
+		{1, false},
+		{2, true},
+		{3, true},
+	}
+	for _, tt := range tests {
+		if _, ok := cache.Get(testCacheKey(tt.index)); ok != tt.hit {
+			t.Errorf("第%d条命中 = %v, 期望 %v", tt.index, ok, tt.hit)
+		}
+	}
+	if _, err := os.Stat(cache.path(testCacheKey(1))); !os.IsNotExist(err) {
+		t.Errorf("被淘汰条目的文件应被删除, err = %v", err)
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 3 || stats.SizeBytes != 30 || stats.Evictions != 1 {
+		t.Fatalf("统计不正确: %+v", stats)
+	}
+	if stats.Hits != 4 || stats.Misses != 1 {
+		t.Fatalf("命中统计不正确: %+v", stats)
+	}
+}
+
+func TestTTSCachePut(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []byte
+		wantEntries int
+	}{
+		{"空数据不缓存", nil, 0},
+		{"超过容量不缓存", bytes.Repeat([]byte("a"), 11), 0},
+		{"正常写入", []byte("audio"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := NewTTSCache(t.TempDir(), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cache.Put(testCacheKey(0), tt.data)
+			if got := cache.Stats().Entries; got != tt.wantEntries {
+				t.Fatalf("条目数 = %d, 期望 %d", got, tt.wantEntries)
+			}
+		})
+	}
+}
+
+func TestTTSCacheOverwriteUpdatesSize(t *testing.T) {
+	cache, err := NewTTSCache(t.TempDir(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := testCacheKey(0)
+	cache.Put(key, []byte("short"))
+	cache.Put(key, []byte("much longer audio"))
+
+	data, ok := cache.Get(key)
+	if !ok || string(data) != "much longer audio" {
+		t.Fatalf("Get() = %q, %v", data, ok)
+	}
+	if stats := cache.Stats(); stats.Entries != 1 || stats.SizeBytes != int64(len("much longer audio")) {
+		t.Fatalf("统计不正确: %+v", stats)
+	}
+}
+
+func TestTTSCacheDropsEntryWhenFileRemoved(t *testing.T) {
+	cache, err := NewTTSCache(t.TempDir(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := testCacheKey(0)
+	cache.Put(key, []byte("audio"))
+	if err := os.Remove(cache.path(key)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("文件已删除时不应命中")
+	}
+	if stats := cache.Stats(); stats.Entries != 0 || stats.SizeBytes != 0 || stats.Misses != 1 {
+		t.Fatalf("统计不正确: %+v", stats)
+	}
+}
+
+func TestNewTTSCacheRestoresFromDisk(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewTTSCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Put(testCacheKey(0), []byte("audio"))
+	cache.Put(testCacheKey(1), []byte("voice"))
+	// 未完成的临时文件不应被当作条目
+	if err := os.WriteFile(cache.path(testCacheKey(0))+".123.tmp", []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewTTSCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats := restored.Stats(); stats.Entries != 2 || stats.SizeBytes != 10 {
+		t.Fatalf("恢复后统计不正确: %+v", stats)
+	}
+	if data, ok := restored.Get(testCacheKey(1)); !ok || string(data) != "voice" {
+		t.Fatalf("Get() = %q, %v", data, ok)
+	}
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/utils/response"
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
@@ -18,9 +21,12 @@ import (
 // 七牛云TTS请求结构体
 type QiniuTTSRequest struct {
 	Audio struct {
-		VoiceType  string  `json:"voice_type"`
-		Encoding   string  `json:"encoding"`
-		SpeedRatio float64 `json:"speed_ratio,omitempty"`
+		VoiceType   string  `json:"voice_type"`
+		Encoding    string  `json:"encoding"`
+		SpeedRatio  float64 `json:"speed_ratio,omitempty"`
+		PitchRatio  float64 `json:"pitch_ratio,omitempty"`
+		VolumeRatio float64 `json:"volume_ratio,omitempty"`
+		Emotion     string  `json:"emotion,omitempty"`
 	} `json:"audio"`
 	Request struct {
 		Text string `json:"text"`
@@ -38,13 +44,8 @@ type QiniuTTSResponse struct {
 	} `json:"addition,omitempty"`
 }
 
-// GenerateQiniuTTS 调用七牛云TTS服务生成语音
-func GenerateQiniuTTS(text string, voiceType string, encoding string, speed float64) ([]byte, error) {
-	apiKey := os.Getenv("QINIU_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("未配置七牛云API密钥")
-	}
-
+// GenerateTTS 按声音所属的提供方合成语音，优先读取缓存
+func GenerateTTS(voice TTSVoice, text string, encoding string, params model.VoiceParams) ([]byte, error) {
 	// 验证文本长度
 	if len([]rune(text)) < 1 {
 		return nil, errors.New("文本不能为空")
@@ -53,24 +54,49 @@ func GenerateQiniuTTS(text string, voiceType string, encoding string, speed floa
 		return nil, errors.New("文本长度不能超过500字")
 	}
 
-	// 构造请求体
-	ttsReq := QiniuTTSRequest{
-		Audio: struct {
-			VoiceType  string  `json:"voice_type"`
-			Encoding   string  `json:"encoding"`
-			SpeedRatio float64 `json:"speed_ratio,omitempty"`
-		}{
-			VoiceType:  voiceType,
-			Encoding:   encoding,
-			SpeedRatio: speed,
-		},
-		Request: struct {
-			Text string `json:"text"`
-		}{
-			Text: text,
-		},
+	// 优先读取缓存
+	cache := getTTSCache()
+	cacheKey := TTSCacheKey(voice.CacheID(), params, encoding, text)
+	if cache != nil {
+		if audioData, ok := cache.Get(cacheKey); ok {
+			return audioData, nil
+		}
+	}
+
+	var audioData []byte
+	var err error
+	if voice.Provider == VoiceCloneProviderHTTP {
+		audioData, err = GenerateCloneTTS(voice.VoiceID, text, encoding, params)
+	} else {
+		audioData, err = GenerateQiniuTTS(text, voice.VoiceID, encoding, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cache != nil {
+		cache.Put(cacheKey, audioData)
+	}
+	return audioData, nil
+}
+
+// GenerateQiniuTTS 调用七牛云TTS服务生成语音
+func GenerateQiniuTTS(text string, voiceType string, encoding string, params model.VoiceParams) ([]byte, error) {
+	apiKey := os.Getenv("QINIU_API_KEY")
+	if apiKey == "" {
+		return nil, errors.New("未配置七牛云API密钥")
 	}
 
+	// 构造请求体
+	var ttsReq QiniuTTSRequest
+	ttsReq.Audio.VoiceType = voiceType
+	ttsReq.Audio.Encoding = encoding
+	ttsReq.Audio.SpeedRatio = params.Speed
+	ttsReq.Audio.PitchRatio = params.Pitch
+	ttsReq.Audio.VolumeRatio = params.Volume
+	ttsReq.Audio.Emotion = params.Emotion
+	ttsReq.Request.Text = text
+
 	jsonData, err := json.Marshal(ttsReq)
 	if err != nil {
 		return nil, fmt.Errorf("JSON序列化失败: %w", err)
@@ -122,17 +148,34 @@ func GenerateQiniuTTS(text string, voiceType string, encoding string, speed floa
 	return audioData, nil
 }
 
-// TTSHandler 处理TTS请求的API端点
+// 支持的音频编码及对应的Content-Type
+var audioContentTypes = map[string]string{
+	"mp3":      "audio/mpeg",
+	"wav":      "audio/wav",
+	"pcm":      "audio/pcm",
+	"ogg_opus": "audio/ogg",
+}
+
+// TTSHandler 处理TTS请求的API端点，直接返回二进制音频
 func TTSHandler(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, response.Unauthorized("用户未认证"))
+		return
+	}
+
 	var request struct {
 		Text     string  `json:"text" binding:"required"`
 		Voice    string  `json:"voice,omitempty"`
 		Encoding string  `json:"encoding,omitempty"`
 		Speed    float64 `json:"speed,omitempty"`
+		Pitch    float64 `json:"pitch,omitempty"`
+		Volume   float64 `json:"volume,omitempty"`
+		Emotion  string  `json:"emotion,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的请求参数"})
+		c.JSON(http.StatusBadRequest, response.BadRequest("无效的请求参数"))
 		return
 	}
 
@@ -143,19 +186,53 @@ func TTSHandler(c *gin.Context) {
 	if request.Encoding == "" {
 		request.Encoding = "mp3"
 	}
-	if request.Speed == 0 {
-		request.Speed = 1.0
+	params := model.DefaultVoiceParams().Merge(&model.VoiceParams{
+		Speed:   request.Speed,
+		Pitch:   request.Pitch,
+		Volume:  request.Volume,
+		Emotion: request.Emotion,
+	})
+
+	// 参数校验
+	uid := userID.(uint)
+	if err := ValidateVoiceType(request.Voice, uid); err != nil {
+		c.JSON(http.StatusBadRequest, response.BadRequest(err.Error()))
+		return
+	}
+	contentType, ok := audioContentTypes[request.Encoding]
+	if !ok {
+		c.JSON(http.StatusBadRequest, response.BadRequest("不支持的音频编码: "+request.Encoding))
+		return
+	}
+	if err := params.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, response.BadRequest(err.Error()))
+		return
+	}
+
+	// 按字符数扣减每日额度
+	chars := int64(len([]rune(request.Text)))
+	limit := int64(config.LoadConfig().TTSDailyCharLimit)
+	if err := ConsumeQuota(QuotaKindTTS, uid, chars, limit); err != nil {
+		if errors.Is(err, ErrQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, response.TooManyRequests("今日语音合成额度已用完"))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, response.InternalError(err.Error()))
+		return
 	}
 
-	audioData, err := GenerateQiniuTTS(request.Text, request.Voice, request.Encoding, request.Speed)
+	audioData, err := GenerateTTS(ResolveTTSVoice(request.Voice), request.Text, request.Encoding, params)
 	if err != nil {
+		RefundQuota(QuotaKindTTS, uid, chars)
 		log.Printf("TTS生成失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "语音生成失败: " + err.Error()})
+		c.JSON(http.StatusInternalServerError, response.InternalError("语音生成失败: "+err.Error()))
 		return
 	}
 
-	// 返回base64编码的音频URL
-	c.JSON(http.StatusOK, gin.H{
-		"audio_url": "data:audio/" + request.Encoding + ";base64," + base64.StdEncoding.EncodeToString(audioData),
-	})
+	c.Data(http.StatusOK, contentType, audioData)
+}
+
+// TTSCacheStatsHandler 返回TTS缓存命中统计
+func TTSCacheStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, response.Success(GetTTSCacheStats()))
 }
@@ -3,7 +3,6 @@ package service
 import (
 	"Backend-CharacterVerse/database"
 	"Backend-CharacterVerse/model"
-	"Backend-CharacterVerse/utils"
 	"errors"
 
 	"golang.org/x/crypto/bcrypt"
@@ -37,7 +36,7 @@ func RegisterUser(username, password string) error {
 }
 
 type LoginResponse struct {
-	Token    string `json:"token"`
+	TokenPair
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 }
@@ -55,16 +54,16 @@ func LoginUser(username, password string) (*LoginResponse, error) {
 		return nil, errors.New("密码错误")
 	}
 
-	// 生成JWT
-	token, err := utils.GenerateToken(user.ID)
+	// 签发访问令牌和刷新令牌
+	tokens, err := startSession(user.ID)
 	if err != nil {
 		return nil, err
 	}
 
 	// 返回包含用户信息的响应
 	return &LoginResponse{
-		Token:    token,
-		UserID:   user.ID,
-		Username: user.Username,
+		TokenPair: *tokens,
+		UserID:    user.ID,
+		Username:  user.Username,
 	}, nil
 }
@@ -7,6 +7,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -36,6 +37,9 @@ type VoiceChatResponse struct {
 	Data    string `json:"data"`     // base64编码的音频数据或错误信息
 	Format  string `json:"format"`   // 音频格式
 	IsFinal bool   `json:"is_final"` // 是否是最后一个片段
+
+	Text    string `json:"text,omitempty"`    // 片段对应的文本（已去除情感标记）
+	Emotion string `json:"emotion,omitempty"` // 片段情感，供前端驱动头像动画
 }
 
 // TTS请求结构
@@ -45,9 +49,12 @@ type TTSRequest struct {
 }
 
 type Audio struct {
-	VoiceType  string  `json:"voice_type"`
-	Encoding   string  `json:"encoding"`
-	SpeedRatio float64 `json:"speed_ratio"`
+	VoiceType   string  `json:"voice_type"`
+	Encoding    string  `json:"encoding"`
+	SpeedRatio  float64 `json:"speed_ratio"`
+	PitchRatio  float64 `json:"pitch_ratio,omitempty"`
+	VolumeRatio float64 `json:"volume_ratio,omitempty"`
+	Emotion     string  `json:"emotion,omitempty"`
 }
 
 type Request struct {
@@ -105,6 +112,12 @@ func HandleVoiceChatSession(conn *websocket.Conn, userID uint) {
 			continue
 		}
 
+		// 私有角色仅创建者可以通话
+		if _, err := GetAccessibleRole(voiceMsg.RoleID, userID); err != nil {
+			sendVoiceError(conn, "获取角色信息失败: "+err.Error())
+			continue
+		}
+
 		// 记录角色ID
 		history.RoleID = voiceMsg.RoleID
 
@@ -145,17 +158,18 @@ func updateVoiceChatHistory(history model.VoiceChatHistory) {
 func processVoiceMessage(ctx context.Context, conn *websocket.Conn, userID uint, msg VoiceChatMessage) error {
 	// 1. 语音识别 - 使用asr_service中的实现
 	log.Printf("开始语音识别: URL=%s, 格式=%s", msg.VoiceURL, msg.Format)
-	userText, err := RecognizeSpeech(msg.VoiceURL, msg.Format)
+	asrResult, err := RecognizeSpeechInput(ctx, ASRInput{URL: msg.VoiceURL, Format: msg.Format})
 	if err != nil {
 		log.Printf("语音识别失败: %v", err)
 		return fmt.Errorf("语音识别失败: %w", err)
 	}
+	userText := asrResult.Text
 
 	log.Printf("语音识别成功! (用户ID: %d, 角色ID: %d): %s", userID, msg.RoleID, userText)
 
 	// 2. 获取角色信息
 	log.Printf("获取角色信息: 角色ID=%d", msg.RoleID)
-	role, err := database.GetRoleByID(msg.RoleID)
+	role, err := GetAccessibleRole(msg.RoleID, userID)
 	if err != nil {
 		log.Printf("获取角色信息失败: %v", err)
 		return fmt.Errorf("获取角色信息失败: %w", err)
@@ -320,10 +334,13 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 	} else {
 		log.Printf("使用角色音色: %s", voiceType)
 	}
+	ttsVoice := ResolveTTSVoice(voiceType)
+
+	voiceParams := role.GetVoiceParams()
 
 	// 构建消息 - 包含历史摘要
 	messages := []map[string]interface{}{
-		{"role": "system", "content": "你正在扮演角色: " + role.Name + "。" + role.Description},
+		{"role": "system", "content": "你正在扮演角色: " + role.Name + "。" + role.Description + emotionPromptInstruction},
 	}
 
 	// 添加历史摘要
@@ -387,19 +404,21 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 	contentCount := 0
 	fragmentCount := 0
 	var wg sync.WaitGroup
-	ttsQueue := make(chan string, 100) // 缓冲队列防止阻塞
+	ttsQueue := make(chan EmotionSegment, 100) // 缓冲队列防止阻塞
+	emotionParser := &emotionStreamParser{}
 
 	// 启动TTS处理goroutine
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		for text := range ttsQueue {
-			if text == "" {
+		for segment := range ttsQueue {
+			text := segment.Text
+			if strings.TrimSpace(text) == "" {
 				continue
 			}
 
 			// 调用TTS生成语音
-			audioData, err := synthesizeSpeech(ttsClient, voiceType, text)
+			audioData, err := synthesizeSpeech(ttsClient, ttsVoice, ApplyEmotion(voiceParams, segment.Emotion), text)
 			if err != nil {
 				log.Printf("生成语音片段失败: %v", err)
 				continue
@@ -413,6 +432,8 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 				Data:    audioData,
 				Format:  "mp3",
 				IsFinal: false, // 流式处理中不是最终片段
+				Text:    text,
+				Emotion: segment.Emotion,
 			}
 
 			respBytes, err := json.Marshal(resp)
@@ -479,7 +500,9 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 						buffer.WriteString(bufferStr[endPos:])
 
 						// 发送到TTS队列
-						ttsQueue <- textFragment
+						for _, segment := range emotionParser.Feed(textFragment) {
+							ttsQueue <- segment
+						}
 					}
 				}
 			}
@@ -488,7 +511,9 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 
 	// 处理剩余的缓冲区内容
 	if buffer.Len() > 0 {
-		ttsQueue <- buffer.String()
+		for _, segment := range emotionParser.Feed(buffer.String()) {
+			ttsQueue <- segment
+		}
 	}
 
 	// 关闭TTS队列并等待处理完成
@@ -518,18 +543,40 @@ func streamAndProcessLLMResponse(ctx context.Context, conn *websocket.Conn, role
 }
 
 // 使用HTTP API合成语音
-func synthesizeSpeech(client *http.Client, voiceType, text string) (string, error) {
+func synthesizeSpeech(client *http.Client, voice TTSVoice, params model.VoiceParams, text string) (string, error) {
+	// 克隆声音只能由克隆服务合成
+	if voice.Provider == VoiceCloneProviderHTTP {
+		audioData, err := GenerateTTS(voice, text, "mp3", params)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(audioData), nil
+	}
+
 	apiKey := os.Getenv("QINIU_API_KEY")
 	if apiKey == "" {
 		return "", errors.New("未配置七牛云API密钥")
 	}
 
+	// 优先读取缓存（缓存中保存的是解码后的音频）
+	cache := getTTSCache()
+	cacheKey := TTSCacheKey(voice.CacheID(), params, "mp3", text)
+	if cache != nil {
+		if audioData, ok := cache.Get(cacheKey); ok {
+			log.Printf("TTS缓存命中: 文本长度=%d", len(text))
+			return base64.StdEncoding.EncodeToString(audioData), nil
+		}
+	}
+
 	// 构建请求
 	ttsRequest := TTSRequest{
 		Audio: Audio{
-			VoiceType:  voiceType,
-			Encoding:   "mp3",
-			SpeedRatio: 1.0,
+			VoiceType:   voice.VoiceID,
+			Encoding:    "mp3",
+			SpeedRatio:  params.Speed,
+			PitchRatio:  params.Pitch,
+			VolumeRatio: params.Volume,
+			Emotion:     params.Emotion,
 		},
 		Request: Request{
 			Text: text,
@@ -572,6 +619,13 @@ func synthesizeSpeech(client *http.Client, voiceType, text string) (string, erro
 	}
 
 	log.Printf("TTS响应成功! 音频大小=%d字节", len(ttsResp.Data))
+
+	if cache != nil {
+		if audioData, err := base64.StdEncoding.DecodeString(ttsResp.Data); err == nil {
+			cache.Put(cacheKey, audioData)
+		}
+	}
+
 	return ttsResp.Data, nil
 }
 
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"Backend-CharacterVerse/storage"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"time"
+)
+
+// 声音克隆提供方类型
+const (
+	VoiceCloneProviderStub = "stub"
+	VoiceCloneProviderHTTP = "http"
+)
+
+// 声音克隆错误分类，接口据此返回不同的状态码
+var (
+	ErrInvalidCloneRequest = errors.New("声音克隆参数无效")
+	ErrVoiceCloneLimit     = errors.New("克隆声音数量已达上限")
+	ErrVoiceCloneFailed    = errors.New("声音克隆服务暂时不可用")
+)
+
+// voiceCloneError 带分类的声音克隆错误：Error 返回面向用户的说明，可用 errors.Is 匹配分类
+type voiceCloneError struct {
+	kind error
+	msg  string
+}
+
+func (e *voiceCloneError) Error() string { return e.msg }
+
+func (e *voiceCloneError) Unwrap() error { return e.kind }
+
+func invalidCloneRequest(msg string) error {
+	return &voiceCloneError{kind: ErrInvalidCloneRequest, msg: msg}
+}
+
+// VoiceCloneRequest 声音克隆请求
+type VoiceCloneRequest struct {
+	UserID   uint
+	Name     string
+	Language string
+	Gender   string
+	Sample   []byte // 参考音频
+	Format   string // 参考音频格式
+}
+
+// VoiceCloner 声音克隆提供方接口，返回提供方侧的声音ID
+type VoiceCloner interface {
+	Name() string
+	Clone(ctx context.Context, req VoiceCloneRequest) (string, error)
+}
+
+// NewVoiceCloner 根据配置创建声音克隆提供方
+func NewVoiceCloner(cfg *config.Config) (VoiceCloner, error) {
+	switch cfg.VoiceCloneProvider {
+	case "", VoiceCloneProviderStub:
+		return &StubVoiceCloner{}, nil
+	case VoiceCloneProviderHTTP:
+		if cfg.VoiceCloneURL == "" {
+			return nil, errors.New("未配置声音克隆服务地址")
+		}
+		return &HTTPVoiceCloner{
+			Endpoint:    cfg.VoiceCloneURL,
+			TTSEndpoint: cfg.VoiceCloneTTSURL,
+			APIKey:      cfg.VoiceCloneAPIKey,
+			Client:      &http.Client{Timeout: 120 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("不支持的声音克隆提供方: %s", cfg.VoiceCloneProvider)
+	}
+}
+
+// StubVoiceCloner 本地桩实现，按性别映射到内置声音，用于开发和测试
+type StubVoiceCloner struct{}
+
+func (s *StubVoiceCloner) Name() string {
+	return VoiceCloneProviderStub
+}
+
+func (s *StubVoiceCloner) Clone(ctx context.Context, req VoiceCloneRequest) (string, error) {
+	if len(req.Sample) == 0 {
+		return "", errors.New("参考音频不能为空")
+	}
+	if req.Gender == model.VoiceGenderMale {
+		return model.VoiceMagneticCourseware, nil
+	}
+	return model.VoiceGentleTeacher, nil
+}
+
+// HTTPVoiceCloner 通用HTTP克隆服务适配器
+// 以multipart上传参考音频，期望返回 {"voice_id": "..."}
+// 合成时以JSON提交声音ID和文本，期望直接返回音频数据
+type HTTPVoiceCloner struct {
+	Endpoint    string
+	TTSEndpoint string
+	APIKey      string
+	Client      *http.Client
+}
+
+func (h *HTTPVoiceCloner) Name() string {
+	return VoiceCloneProviderHTTP
+}
+
+func (h *HTTPVoiceCloner) Clone(ctx context.Context, req VoiceCloneRequest) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", "sample."+req.Format)
+	if err != nil {
+		return "", fmt.Errorf("创建表单文件失败: %w", err)
+	}
+	if _, err := part.Write(req.Sample); err != nil {
+		return "", fmt.Errorf("写入音频数据失败: %w", err)
+	}
+	_ = writer.WriteField("name", req.Name)
+	_ = writer.WriteField("language", req.Language)
+	_ = writer.WriteField("gender", req.Gender)
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("关闭表单写入器失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.Endpoint, body)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	if h.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	resp, err := h.Client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("克隆请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("克隆服务返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		VoiceID string `json:"voice_id"`
+	}
+	if err := json.Unmarshal(bodyBytes, &result); err != nil {
+		return "", fmt.Errorf("解析克隆响应失败: %w", err)
+	}
+	if result.VoiceID == "" {
+		return "", errors.New("克隆服务未返回声音ID")
+	}
+	return result.VoiceID, nil
+}
+
+// Synthesize 使用克隆服务合成语音
+func (h *HTTPVoiceCloner) Synthesize(ctx context.Context, voiceID, text, encoding string, params model.VoiceParams) ([]byte, error) {
+	if h.TTSEndpoint == "" {
+		return nil, errors.New("未配置克隆声音合成服务地址")
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"voice_id": voiceID,
+		"text":     text,
+		"encoding": encoding,
+		"speed":    params.Speed,
+		"pitch":    params.Pitch,
+		"volume":   params.Volume,
+		"emotion":  params.Emotion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("JSON序列化失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", h.TTSEndpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if h.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+h.APIKey)
+	}
+
+	resp, err := h.Client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("合成请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("克隆服务返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+	}
+	if len(bodyBytes) == 0 {
+		return nil, errors.New("克隆服务未返回音频数据")
+	}
+	return bodyBytes, nil
+}
+
+// GenerateCloneTTS 使用HTTP克隆服务合成克隆声音的语音
+func GenerateCloneTTS(voiceID, text, encoding string, params model.VoiceParams) ([]byte, error) {
+	cfg := config.LoadConfig()
+	cloner := &HTTPVoiceCloner{
+		TTSEndpoint: cfg.VoiceCloneTTSURL,
+		APIKey:      cfg.VoiceCloneAPIKey,
+		Client:      &http.Client{Timeout: 60 * time.Second},
+	}
+	return cloner.Synthesize(context.Background(), voiceID, text, encoding, params)
+}
+
+// CloneVoice 克隆声音并保存为用户私有声音
+func CloneVoice(ctx context.Context, req VoiceCloneRequest) (*model.Voice, error) {
+	cfg := config.LoadConfig()
+
+	if req.Name == "" {
+		return nil, invalidCloneRequest("声音名称不能为空")
+	}
+	if req.Language == "" {
+		req.Language = model.VoiceLanguageChinese
+	}
+	if req.Gender == "" {
+		req.Gender = model.VoiceGenderUnknown
+	}
+	if !validVoiceLanguages[req.Language] {
+		return nil, invalidCloneRequest("无效的声音语言")
+	}
+	if !validVoiceGenders[req.Gender] {
+		return nil, invalidCloneRequest("无效的声音性别")
+	}
+	if format := DetectAudioFormat(req.Sample); format == "" || format != req.Format {
+		return nil, invalidCloneRequest("参考音频内容与格式不符")
+	}
+
+	count, err := database.CountVoicesByOwner(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.VoiceCloneMaxPerUser > 0 && count >= int64(cfg.VoiceCloneMaxPerUser) {
+		return nil, &voiceCloneError{kind: ErrVoiceCloneLimit, msg: fmt.Sprintf("每个用户最多克隆%d个声音", cfg.VoiceCloneMaxPerUser)}
+	}
+
+	cloner, err := NewVoiceCloner(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	providerVoiceID, err := cloner.Clone(ctx, req)
+	if err != nil {
+		// 提供方的原始响应只记录日志，不返回给用户
+		log.Printf("声音克隆失败: 用户ID=%d, 提供方=%s, 错误=%v", req.UserID, cloner.Name(), err)
+		return nil, ErrVoiceCloneFailed
+	}
+
+	// 参考音频同时作为试听音频保存，计入用户的存储配额
+	sampleKey, err := uploadAudio(storage.WithUploader(ctx, req.UserID), model.ObjectKindVoiceSample, req.UserID, req.Sample, req.Format)
+	if err != nil {
+		return nil, fmt.Errorf("上传参考音频失败: %w", err)
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	voice := model.Voice{
+		VoiceType:       fmt.Sprintf("clone_%d_%s", req.UserID, hex.EncodeToString(suffix)),
+		VoiceName:       req.Name,
+		URL:             model.BlobRef(sampleKey),
+		Category:        model.VoiceCategoryCloned,
+		Provider:        cloner.Name(),
+		Language:        req.Language,
+		Gender:          req.Gender,
+		Enabled:         true,
+		OwnerID:         req.UserID,
+		ProviderVoiceID: providerVoiceID,
+	}
+	if err := database.DB.Create(&voice).Error; err != nil {
+		return nil, err
+	}
+	return &voice, nil
+}
+
+// ListClonedVoices 获取用户克隆的声音
+func ListClonedVoices(userID uint) ([]model.Voice, error) {
+	var voices []model.Voice
+	err := database.DB.Where("owner_id = ?", userID).Order("created_at DESC").Find(&voices).Error
+	return voices, err
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"Backend-CharacterVerse/model"
+	"context"
+	"errors"
+	"testing"
+)
+
+// 最小的WAV文件头，足以通过内容识别
+var testWAVSample = append([]byte("RIFF\x24\x00\x00\x00WAVEfmt "), make([]byte, 32)...)
+
+func TestCloneVoiceUsableOnlyByOwner(t *testing.T) {
+	setupTestDB(t, &model.Voice{}, &model.StoredObject{})
+	setupTestStorage(t)
+	t.Setenv("VOICE_CLONE_PROVIDER", VoiceCloneProviderStub)
+
+	const ownerID, otherID = 1, 2
+	voice, err := CloneVoice(context.Background(), VoiceCloneRequest{
+		UserID: ownerID,
+		Name:   "我的声音",
+		Gender: model.VoiceGenderMale,
+		Sample: testWAVSample,
+		Format: "wav",
+	})
+	if err != nil {
+		t.Fatalf("克隆声音失败: %v", err)
+	}
+	if voice.OwnerID != ownerID || voice.Provider != VoiceCloneProviderStub {
+		t.Fatalf("克隆声音归属错误: owner=%d provider=%s", voice.OwnerID, voice.Provider)
+	}
+
+	tests := []struct {
+		name    string
+		userID  uint
+		wantErr bool
+	}{
+		{"创建者的角色可以使用", ownerID, false},
+		{"其他用户的角色不能使用", otherID, true},
+		{"未登录不能使用", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVoiceType(voice.VoiceType, tt.userID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateVoiceType(%q, %d) 错误 = %v, 期望出错 %v", voice.VoiceType, tt.userID, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloneVoiceRejectsInvalidRequests(t *testing.T) {
+	setupTestDB(t, &model.Voice{}, &model.StoredObject{})
+	setupTestStorage(t)
+	t.Setenv("VOICE_CLONE_PROVIDER", VoiceCloneProviderStub)
+
+	valid := VoiceCloneRequest{UserID: 1, Name: "声音", Sample: testWAVSample, Format: "wav"}
+	tests := []struct {
+		name   string
+		modify func(*VoiceCloneRequest)
+	}{
+		{"名称为空", func(r *VoiceCloneRequest) { r.Name = "" }},
+		{"语言无效", func(r *VoiceCloneRequest) { r.Language = "xx" }},
+		{"性别无效", func(r *VoiceCloneRequest) { r.Gender = "xx" }},
+		{"内容与格式不符", func(r *VoiceCloneRequest) { r.Format = "mp3" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if _, err := CloneVoice(context.Background(), req); !errors.Is(err, ErrInvalidCloneRequest) {
+				t.Fatalf("错误 = %v, 期望 ErrInvalidCloneRequest", err)
+			}
+		})
+	}
+}
+
+func TestCloneVoiceLimitPerUser(t *testing.T) {
+	setupTestDB(t, &model.Voice{}, &model.StoredObject{})
+	setupTestStorage(t)
+	t.Setenv("VOICE_CLONE_PROVIDER", VoiceCloneProviderStub)
+	t.Setenv("VOICE_CLONE_MAX_PER_USER", "1")
+
+	req := VoiceCloneRequest{UserID: 1, Name: "声音", Sample: testWAVSample, Format: "wav"}
+	if _, err := CloneVoice(context.Background(), req); err != nil {
+		t.Fatalf("首次克隆失败: %v", err)
+	}
+	if _, err := CloneVoice(context.Background(), req); !errors.Is(err, ErrVoiceCloneLimit) {
+		t.Fatalf("错误 = %v, 期望 ErrVoiceCloneLimit", err)
+	}
+}
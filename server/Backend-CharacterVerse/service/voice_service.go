@@ -0,0 +1,136 @@
+package service
+
+import (
+	"Backend-CharacterVerse/database"
+	"Backend-CharacterVerse/model"
+	"errors"
+)
+
+// 预定义有效的声音语言和性别
+var (
+	validVoiceLanguages = map[string]bool{
+		model.VoiceLanguageChinese: true, model.VoiceLanguageEnglish: true, model.VoiceLanguageMulti: true,
+	}
+	validVoiceGenders = map[string]bool{
+		model.VoiceGenderMale: true, model.VoiceGenderFemale: true, model.VoiceGenderUnknown: true,
+	}
+)
+
+// ValidateVoiceType 校验声音类型在声音目录中存在、已启用且该用户可以使用
+func ValidateVoiceType(voiceType string, userID uint) error {
+	voice, err := database.GetVoiceByType(voiceType)
+	if err != nil || !voice.UsableBy(userID) {
+		return errors.New("无效的声音类型")
+	}
+	return nil
+}
+
+// TTSVoice 合成时使用的提供方及提供方侧的声音标识
+type TTSVoice struct {
+	Provider string
+	VoiceID  string
+}
+
+// CacheID 用于缓存键的声音标识，避免不同提供方的同名声音互相命中
+func (v TTSVoice) CacheID() string {
+	if v.Provider == VoiceCloneProviderHTTP {
+		return v.Provider + ":" + v.VoiceID
+	}
+	return v.VoiceID
+}
+
+// ResolveTTSVoice 将声音类型转换为合成时使用的提供方及声音标识
+// 桩克隆的声音映射到七牛云内置声音，仍由七牛云合成
+func ResolveTTSVoice(voiceType string) TTSVoice {
+	voice, err := database.GetVoiceByType(voiceType)
+	if err != nil {
+		return TTSVoice{Provider: model.VoiceProviderQiniu, VoiceID: voiceType}
+	}
+	return TTSVoice{Provider: voice.Provider, VoiceID: voice.TTSVoiceType()}
+}
+
+// ListVoices 查询声音目录
+func ListVoices(filter database.VoiceFilter) ([]model.Voice, error) {
+	return database.ListVoices(filter)
+}
+
+// AddVoice 向声音目录添加声音
+func AddVoice(voice model.Voice) (*model.Voice, error) {
+	if voice.VoiceType == "" || voice.VoiceName == "" {
+		return nil, errors.New("声音类型标识和名称不能为空")
+	}
+	if voice.Provider == "" {
+		voice.Provider = model.VoiceProviderQiniu
+	}
+	if voice.Language == "" {
+		voice.Language = model.VoiceLanguageChinese
+	}
+	if voice.Gender == "" {
+		voice.Gender = model.VoiceGenderUnknown
+	}
+	if !validVoiceLanguages[voice.Language] {
+		return nil, errors.New("无效的声音语言")
+	}
+	if !validVoiceGenders[voice.Gender] {
+		return nil, errors.New("无效的声音性别")
+	}
+
+	if _, err := database.GetVoiceByType(voice.VoiceType); err == nil {
+		return nil, errors.New("声音类型已存在")
+	}
+
+	voice.Enabled = true
+	if err := database.DB.Create(&voice).Error; err != nil {
+		return nil, err
+	}
+	return &voice, nil
+}
+
+// UpdateVoice 更新声音目录中的声音（包括启用/禁用）
+func UpdateVoice(voiceType string, updates map[string]interface{}) error {
+	voice, err := database.GetVoiceByType(voiceType)
+	if err != nil {
+		return err
+	}
+
+	// 验证更新字段
+	validFields := map[string]bool{
+		"voice_name": true,
+		"sample_url": true,
+		"category":   true,
+		"provider":   true,
+		"language":   true,
+		"gender":     true,
+		"enabled":    true,
+	}
+
+	// 过滤无效字段，并将字段名映射为数据库列名
+	cleanUpdates := make(map[string]interface{})
+	for key, value := range updates {
+		if !validFields[key] {
+			continue
+		}
+		if key == "sample_url" {
+			key = "url"
+		}
+		cleanUpdates[key] = value
+	}
+
+	if language, ok := cleanUpdates["language"]; ok {
+		if languageStr, isString := language.(string); !isString || !validVoiceLanguages[languageStr] {
+			return errors.New("无效的声音语言")
+		}
+	}
+	if gender, ok := cleanUpdates["gender"]; ok {
+		if genderStr, isString := gender.(string); !isString || !validVoiceGenders[genderStr] {
+			return errors.New("无效的声音性别")
+		}
+	}
+	if enabled, ok := cleanUpdates["enabled"]; ok {
+		if _, isBool := enabled.(bool); !isBool {
+			return errors.New("enabled必须为布尔值")
+		}
+	}
+
+	return database.DB.Model(voice).Updates(cleanUpdates).Error
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// whisper.cpp server 的 verbose_json 响应结构
+type whisperResponse struct {
+	Language                    string  `json:"language"`
+	DetectedLanguage            string  `json:"detected_language"`
+	DetectedLanguageProbability float64 `json:"detected_language_probability"`
+	Duration                    float64 `json:"duration"`
+	Text                        string  `json:"text"`
+	Segments                    []struct {
+		Text       string  `json:"text"`
+		Start      float64 `json:"start"`
+		End        float64 `json:"end"`
+		AvgLogprob float64 `json:"avg_logprob"`
+		Words      []struct {
+			Word        string  `json:"word"`
+			Start       float64 `json:"start"`
+			End         float64 `json:"end"`
+			Probability float64 `json:"probability"`
+		} `json:"words"`
+	} `json:"segments"`
+}
+
+// WhisperRecognizer 本地 whisper.cpp 风格 HTTP 服务适配器
+type WhisperRecognizer struct {
+	Endpoint string // 如 http://127.0.0.1:8178/inference
+	Language string // 为空时自动检测
+	Client   *http.Client
+}
+
+func (r *WhisperRecognizer) Name() string {
+	return ASREngineWhisper
+}
+
+func (r *WhisperRecognizer) Recognize(ctx context.Context, input ASRInput) (*ASRResult, error) {
+	if r.Endpoint == "" {
+		return nil, errors.New("未配置Whisper服务地址")
+	}
+
+	// Whisper服务需要原始音频，URL输入先下载
+	audioData := input.Data
+	if len(audioData) == 0 {
+		if input.URL == "" {
+			return nil, errors.New("音频URL不能为空")
+		}
+		data, err := r.download(ctx, input.URL)
+		if err != nil {
+			return nil, fmt.Errorf("下载音频失败: %w", err)
+		}
+		audioData = data
+	}
+
+	format := input.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	// 构造表单
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", "audio."+format)
+	if err != nil {
+		return nil, fmt.Errorf("创建表单文件失败: %w", err)
+	}
+	if _, err := part.Write(audioData); err != nil {
+		return nil, fmt.Errorf("写入音频数据失败: %w", err)
+	}
+	_ = writer.WriteField("response_format", "verbose_json")
+	_ = writer.WriteField("temperature", "0.0")
+	if r.Language != "" {
+		_ = writer.WriteField("language", r.Language)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("关闭表单写入器失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", r.Endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Whisper请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Whisper服务返回错误状态码: %d, 响应: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResponse whisperResponse
+	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
+		return nil, fmt.Errorf("解析Whisper响应失败: %w", err)
+	}
+
+	return convertWhisperResponse(apiResponse, r.Name())
+}
+
+// 下载URL指向的音频
+func (r *WhisperRecognizer) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("状态码 %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxASRUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxASRUploadSize {
+		return nil, fmt.Errorf("音频不能超过%dMB", maxASRUploadSize>>20)
+	}
+	return data, nil
+}
+
+// 将whisper响应转换为统一的识别结果
+func convertWhisperResponse(apiResponse whisperResponse, engine string) (*ASRResult, error) {
+	text := strings.TrimSpace(apiResponse.Text)
+	if text == "" {
+		// 部分版本只在segments中返回文本
+		var builder strings.Builder
+		for _, segment := range apiResponse.Segments {
+			builder.WriteString(segment.Text)
+		}
+		text = strings.TrimSpace(builder.String())
+	}
+	if text == "" {
+		return nil, errors.New("未识别到有效文本")
+	}
+
+	result := &ASRResult{
+		Text:     text,
+		Language: apiResponse.DetectedLanguage,
+		Duration: apiResponse.Duration,
+		Engine:   engine,
+	}
+	if result.Language == "" {
+		result.Language = apiResponse.Language
+	}
+
+	// 置信度优先使用单词概率平均值，否则使用段落平均对数概率
+	var probabilitySum, logprobSum float64
+	for _, segment := range apiResponse.Segments {
+		logprobSum += segment.AvgLogprob
+		for _, word := range segment.Words {
+			result.Words = append(result.Words, WordTimestamp{
+				Word:       strings.TrimSpace(word.Word),
+				Start:      word.Start,
+				End:        word.End,
+				Confidence: word.Probability,
+			})
+			probabilitySum += word.Probability
+		}
+	}
+
+	switch {
+	case len(result.Words) > 0:
+		result.Confidence = probabilitySum / float64(len(result.Words))
+	case len(apiResponse.Segments) > 0:
+		result.Confidence = math.Exp(logprobSum / float64(len(apiResponse.Segments)))
+	}
+
+	return result, nil
+}
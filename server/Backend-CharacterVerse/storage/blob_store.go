@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"Backend-CharacterVerse/config"
+	"Backend-CharacterVerse/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 对象存储后端类型
+const (
+	BackendLocal         = "local"
+	BackendUploadService = "upload_service"
+	BackendS3            = "s3"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// BlobStore 对象存储接口，数据库中只保存对象键，访问地址在读取时解析
+type BlobStore interface {
+	Name() string
+	// Put 保存对象并返回实际的对象键（部分后端会自行重命名）
+	Put(ctx context.Context, key string, data []byte, contentType string) (string, error)
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+	// URL 对象的访问地址
+	URL(key string) string
+	// KeyFromURL 从本后端生成的访问地址中还原对象键
+	KeyFromURL(rawURL string) (string, bool)
+}
+
+var defaultStore BlobStore
+
+type uploaderKey struct{}
+
+// WithUploader 指定上传文件计入哪个用户的存储配额，未指定时按系统身份上传
+func WithUploader(ctx context.Context, userID uint) context.Context {
+	return context.WithValue(ctx, uploaderKey{}, userID)
+}
+
+// UploaderFromContext 获取上传者用户ID，未指定时为系统用户
+func UploaderFromContext(ctx context.Context) uint {
+	if userID, ok := ctx.Value(uploaderKey{}).(uint); ok {
+		return userID
+	}
+	return utils.UploadSystemUserID
+}
+
+// NewBlobStore 根据配置创建并校验对象存储
+func NewBlobStore(cfg *config.Config) (BlobStore, error) {
+	switch cfg.BlobStore {
+	case BackendLocal:
+		// 本地文件由后端校验签名后提供访问
+		if cfg.UploadTokenSecret == "" {
+			return nil, errors.New("使用本地存储时必须配置 UPLOAD_TOKEN_SECRET 用于签名文件访问链接")
+		}
+		if err := os.MkdirAll(cfg.BlobLocalDir, 0755); err != nil {
+			return nil, fmt.Errorf("创建本地存储目录失败: %w", err)
+		}
+		return NewLocalStore(cfg.BlobLocalDir, cfg.BlobPublicURL), nil
+	case "", BackendUploadService:
+		if cfg.UploadServiceURL == "" {
+			return nil, errors.New("未配置上传服务地址")
+		}
+		if cfg.UploadTokenSecret == "" {
+			return nil, errors.New("使用上传服务存储时必须配置 UPLOAD_TOKEN_SECRET")
+		}
+		return NewUploadServiceStore(cfg.UploadServiceURL), nil
+	case BackendS3:
+		return NewS3Store(S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+			URLExpiry: time.Duration(cfg.SignedURLTTLMinutes) * time.Minute,
+		})
+	default:
+		return nil, fmt.Errorf("不支持的对象存储类型: %s", cfg.BlobStore)
+	}
+}
+
+// Init 根据配置初始化全局对象存储，需在启动时调用
+func Init(cfg *config.Config) error {
+	store, err := NewBlobStore(cfg)
+	if err != nil {
+		return err
+	}
+	defaultStore = store
+	log.Printf("对象存储已初始化: %s", store.Name())
+	return nil
+}
+
+// Default 获取全局对象存储
+func Default() BlobStore {
+	if defaultStore == nil {
+		panic("对象存储未初始化，请先调用 storage.Init")
+	}
+	return defaultStore
+}
+
+// NewKey 生成对象键：前缀/日期/随机串.扩展名
+func NewKey(prefix, ext string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return path.Join(prefix, time.Now().Format("20060102"), hex.EncodeToString(buf)+ext)
+}
+
+// Put 保存到全局对象存储
+func Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	return Default().Put(ctx, key, data, contentType)
+}
+
+// Get 从全局对象存储读取，兼容旧数据中保存的完整URL
+func Get(ctx context.Context, ref string) ([]byte, error) {
+	store := Default()
+	if isAbsoluteURL(ref) {
+		key, ok := store.KeyFromURL(ref)
+		if !ok {
+			return nil, fmt.Errorf("不属于当前对象存储的地址: %s", ref)
+		}
+		ref = key
+	}
+	return store.Get(ctx, ref)
+}
+
+// Delete 从全局对象存储删除
+func Delete(ctx context.Context, key string) error {
+	return Default().Delete(ctx, key)
+}
+
+// ResolveURL 将对象键解析为访问地址，外部URL原样返回
+func ResolveURL(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	store := Default()
+	if isAbsoluteURL(ref) {
+		key, ok := store.KeyFromURL(ref)
+		if !ok {
+			return ref
+		}
+		ref = key
+	}
+	return store.URL(ref)
+}
+
+// OwnedKey 将对象键或本存储生成的访问地址解析为对象键，其他地址返回错误
+func OwnedKey(ref string) (string, error) {
+	key := NormalizeRef(strings.TrimSpace(ref))
+	if isAbsoluteURL(key) {
+		return "", fmt.Errorf("不属于当前对象存储的地址: %s", ref)
+	}
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// NormalizeRef 将本存储生成的访问地址还原为对象键，其他值原样返回
+func NormalizeRef(ref string) string {
+	if isAbsoluteURL(ref) {
+		if key, ok := Default().KeyFromURL(ref); ok {
+			return key
+		}
+	}
+	return ref
+}
+
+// 为地址附加文件访问签名，密钥在启动时已校验，签名失败时返回无法访问的原地址
+func signedURL(rawURL, key string) string {
+	query, err := utils.SignFileQuery(key)
+	if err != nil {
+		log.Printf("签名文件访问链接失败: 对象键=%s, 错误=%v", key, err)
+		return rawURL
+	}
+	return rawURL + "?" + query
+}
+
+func isAbsoluteURL(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
+
+// 从以base为前缀的地址中截取对象键，忽略查询参数
+func trimBaseURL(rawURL, base string) (string, bool) {
+	base = strings.TrimRight(base, "/") + "/"
+	if base == "/" || !strings.HasPrefix(rawURL, base) {
+		return "", false
+	}
+	key := strings.TrimPrefix(rawURL, base)
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	return key, key != ""
+}
+
+// 校验对象键，禁止跳出存储目录
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("无效的对象键: %s", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("无效的对象键: %s", key)
+		}
+	}
+	return nil
+}
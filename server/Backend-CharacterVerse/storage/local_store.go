@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore 本地文件系统存储，由后端校验访问签名后对外提供
+type LocalStore struct {
+	Dir       string // 存储根目录
+	PublicURL string // 对外访问前缀
+}
+
+func NewLocalStore(dir, publicURL string) *LocalStore {
+	return &LocalStore{Dir: dir, PublicURL: strings.TrimRight(publicURL, "/")}
+}
+
+func (s *LocalStore) Name() string {
+	return BackendLocal
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+
+	// 先写唯一命名的临时文件再重命名，避免读到写了一半的文件或并发写入互相覆盖
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// FilePath 对象键对应的本地文件路径
+func (s *LocalStore) FilePath(key string) (string, error) {
+	return s.path(key)
+}
+
+// URL 带签名的访问链接，每次读取时重新签发
+func (s *LocalStore) URL(key string) string {
+	return signedURL(s.PublicURL+"/"+key, key)
+}
+
+func (s *LocalStore) KeyFromURL(rawURL string) (string, bool) {
+	return trimBaseURL(rawURL, s.PublicURL)
+}
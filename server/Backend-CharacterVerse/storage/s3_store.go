@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options S3兼容存储配置
+type S3Options struct {
+	Endpoint  string // 服务地址，如 http://127.0.0.1:9000
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PublicURL string        // 对外访问前缀，为空时使用 Endpoint/Bucket；反向代理需保留Host和路径，否则预签名校验失败
+	URLExpiry time.Duration // 预签名访问链接有效期
+}
+
+// 预签名链接的签名时间取整粒度，同一时间段内生成的链接相同，便于浏览器缓存
+const s3PresignGranularity = 5 * time.Minute
+
+// SigV4 预签名链接的最长有效期
+const s3MaxPresignExpiry = 7 * 24 * time.Hour
+
+// S3Store S3兼容存储（如MinIO），使用路径风格访问并以SigV4签名
+type S3Store struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func NewS3Store(opts S3Options) (*S3Store, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("S3存储需要配置服务地址和存储桶")
+	}
+	// 存储桶不对外公开，访问链接需用密钥预签名
+	if opts.AccessKey == "" || opts.SecretKey == "" {
+		return nil, errors.New("S3存储需要配置访问密钥")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(opts.Endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("无效的S3服务地址: %w", err)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.PublicURL == "" {
+		opts.PublicURL = endpoint.String() + "/" + opts.Bucket
+	}
+	opts.PublicURL = strings.TrimRight(opts.PublicURL, "/")
+	if _, err := url.Parse(opts.PublicURL); err != nil {
+		return nil, fmt.Errorf("无效的S3对外访问地址: %w", err)
+	}
+	if opts.URLExpiry <= 0 {
+		opts.URLExpiry = time.Hour
+	}
+	if opts.URLExpiry > s3MaxPresignExpiry-s3PresignGranularity {
+		opts.URLExpiry = s3MaxPresignExpiry - s3PresignGranularity
+	}
+
+	return &S3Store{
+		opts:     opts,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 120 * time.Second},
+	}, nil
+}
+
+func (s *S3Store) Name() string {
+	return BackendS3
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, data, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("S3上传失败: 状态码 %d, 响应: %s", resp.StatusCode, string(body))
+	}
+	return key, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("S3读取失败: 状态码 %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("S3删除失败: 状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// URL 预签名的GET访问链接，每次读取时重新签发
+func (s *S3Store) URL(key string) string {
+	return s.presignGet(key, time.Now().UTC())
+}
+
+func (s *S3Store) KeyFromURL(rawURL string) (string, bool) {
+	key, ok := trimBaseURL(rawURL, s.opts.PublicURL)
+	if !ok {
+		return "", false
+	}
+	unescaped, err := url.PathUnescape(key)
+	if err != nil {
+		return "", false
+	}
+	return unescaped, true
+}
+
+// 发送签名请求
+func (s *S3Store) do(ctx context.Context, method, key string, body []byte, header http.Header) (*http.Response, error) {
+	objectPath := s.endpoint.Path + "/" + s.opts.Bucket + "/" + key
+	req, err := http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+s.endpoint.Host+escapeS3Path(objectPath), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.sign(req, body, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// sign 按AWS Signature V4为请求签名
+func (s *S3Store) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		signedHeaders = []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+		canonicalHeaders = "content-type:" + contentType + "\n" + canonicalHeaders
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(date), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+// presignGet 按AWS Signature V4生成查询参数签名的GET链接，签名基于对外访问地址
+func (s *S3Store) presignGet(key string, now time.Time) string {
+	// 签名时间向下取整，有效期相应延长一个粒度
+	now = now.Truncate(s3PresignGranularity)
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+
+	public, _ := url.Parse(s.opts.PublicURL)
+	objectPath := escapeS3Path(public.Path + "/" + key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.opts.AccessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int((s.opts.URLExpiry+s3PresignGranularity)/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	// S3要求空格编码为%20
+	canonicalQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		objectPath,
+		canonicalQuery,
+		"host:" + public.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(s.signingKey(date), stringToSign))
+
+	return public.Scheme + "://" + public.Host + objectPath + "?" + canonicalQuery + "&X-Amz-Signature=" + signature
+}
+
+// SigV4 签名密钥
+func (s *S3Store) signingKey(date string) []byte {
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+// 按S3规则逐段转义路径
+func escapeS3Path(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = strings.ReplaceAll(url.PathEscape(part), "+", "%2B")
+	}
+	return strings.Join(parts, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
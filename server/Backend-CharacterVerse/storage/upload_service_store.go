@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"Backend-CharacterVerse/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// UploadServiceStore 通过 Upload_Voice_Service 上传文件，对象键为服务返回的文件名（按内容哈希分目录）
+type UploadServiceStore struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewUploadServiceStore(baseURL string) *UploadServiceStore {
+	return &UploadServiceStore{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Client:  &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+func (s *UploadServiceStore) Name() string {
+	return BackendUploadService
+}
+
+func (s *UploadServiceStore) Put(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	// 创建表单数据
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	part, err := writer.CreateFormFile("file", path.Base(key))
+	if err != nil {
+		return "", fmt.Errorf("创建表单文件失败: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", fmt.Errorf("写入文件数据失败: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("关闭表单写入器失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+"/api/upload", body)
+	if err != nil {
+		return "", fmt.Errorf("创建上传请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := authorizeUpload(req, UploaderFromContext(ctx)); err != nil {
+		return "", err
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("上传请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("上传失败: 状态码 %d, 响应: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		Message  string `json:"message"`
+		Filename string `json:"filename"`
+		URL      string `json:"url"`
+		Error    string `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("解析上传响应失败: %v, 响应体: %s", err, string(respBody))
+	}
+	if result.Error != "" {
+		return "", errors.New("上传失败: " + result.Error)
+	}
+	if result.Filename == "" {
+		return "", errors.New("上传失败: 响应缺少文件名")
+	}
+	return result.Filename, nil
+}
+
+func (s *UploadServiceStore) Get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取文件失败: 状态码 %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *UploadServiceStore) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.BaseURL+"/api/files/"+key, nil)
+	if err != nil {
+		return err
+	}
+	if err := authorizeUpload(req, UploaderFromContext(ctx)); err != nil {
+		return err
+	}
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("删除失败: 状态码 %d, 响应: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// URL 上传服务只接受带签名的访问链接，每次读取时重新签发
+func (s *UploadServiceStore) URL(key string) string {
+	return signedURL(s.BaseURL+"/uploads/"+key, key)
+}
+
+func (s *UploadServiceStore) KeyFromURL(rawURL string) (string, bool) {
+	return trimBaseURL(rawURL, s.BaseURL+"/uploads")
+}
+
+// 以上传者身份签发令牌：用户上传的文件计入其配额，系统生成的文件（uid为0）不受配额限制
+// 上传服务按上传者记录引用，删除时需使用上传时的身份
+func authorizeUpload(req *http.Request, userID uint) error {
+	token, _, err := utils.GenerateUploadToken(userID)
+	if err != nil {
+		return fmt.Errorf("签发上传令牌失败: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
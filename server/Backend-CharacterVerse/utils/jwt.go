@@ -2,25 +2,67 @@ package utils
 
 import (
 	"Backend-CharacterVerse/config"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 )
 
-func GenerateToken(userID uint) (string, error) {
+// AccessClaims 访问令牌声明
+type AccessClaims struct {
+	UserID    uint   `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`    // 登录会话ID，刷新令牌后保持不变
+	IssuedMs  int64  `json:"iat_ms,omitempty"` // 毫秒精度的签发时间，iat 只精确到秒，无法区分同一秒内注销前后签发的令牌
+	jwt.RegisteredClaims
+}
+
+// GenerateToken 签发短期访问令牌，返回令牌及过期时间
+func GenerateToken(userID uint, sessionID string) (string, time.Time, error) {
 	cfg := config.LoadConfig()
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(cfg.AccessTokenTTLMinutes) * time.Minute)
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		IssuedMs:  now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        RandomID(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	})
 
-	return token.SignedString([]byte(cfg.JWTSecret))
+	signed, err := token.SignedString([]byte(cfg.JWTSecret))
+	return signed, expiresAt, err
 }
 
-func ParseToken(tokenString string) (*jwt.Token, error) {
+// ParseToken 校验签名和有效期并解析访问令牌
+func ParseToken(tokenString string) (*AccessClaims, error) {
 	cfg := config.LoadConfig()
-	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims := &AccessClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("不支持的签名算法")
+		}
 		return []byte(cfg.JWTSecret), nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("无效的令牌")
+	}
+	return claims, nil
+}
+
+// RandomID 生成随机ID（32位十六进制）
+func RandomID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
 }
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// GB2312一级汉字按拼音排序，各声母首字的编码即为区间起点
+var pinyinInitialBoundaries = []struct {
+	code    int
+	initial byte
+}{
+	{0xB0A1, 'a'}, {0xB0C5, 'b'}, {0xB2C1, 'c'}, {0xB4EE, 'd'}, {0xB6EA, 'e'},
+	{0xB7A2, 'f'}, {0xB8C1, 'g'}, {0xB9FE, 'h'}, {0xBBF7, 'j'}, {0xBFA6, 'k'},
+	{0xC0AC, 'l'}, {0xC2E8, 'm'}, {0xC4C3, 'n'}, {0xC5B6, 'o'}, {0xC5BE, 'p'},
+	{0xC6DA, 'q'}, {0xC8BB, 'r'}, {0xC8F6, 's'}, {0xCBFA, 't'}, {0xCDDA, 'w'},
+	{0xCEF4, 'x'}, {0xD1B9, 'y'}, {0xD4D1, 'z'},
+}
+
+// 一级汉字编码上限，二级汉字按部首排序无法推算拼音
+const pinyinLevelOneEnd = 0xD7F9
+
+// PinyinInitial 获取单个汉字的拼音首字母，无法识别时返回0
+func PinyinInitial(r rune) byte {
+	encoded, err := simplifiedchinese.GBK.NewEncoder().String(string(r))
+	if err != nil || len(encoded) != 2 {
+		return 0
+	}
+	code := int(encoded[0])<<8 | int(encoded[1])
+	if code < pinyinInitialBoundaries[0].code || code > pinyinLevelOneEnd {
+		return 0
+	}
+
+	initial := pinyinInitialBoundaries[0].initial
+	for _, boundary := range pinyinInitialBoundaries {
+		if code < boundary.code {
+			break
+		}
+		initial = boundary.initial
+	}
+	return initial
+}
+
+// PinyinInitials 获取文本的拼音首字母，字母和数字原样保留（转为小写），其他字符忽略
+// 例如 "李白Bot" -> "lbbot"
+func PinyinInitials(text string) string {
+	var builder strings.Builder
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			builder.WriteRune(unicode.ToLower(r))
+		case unicode.Is(unicode.Han, r):
+			if initial := PinyinInitial(r); initial != 0 {
+				builder.WriteByte(initial)
+			}
+		}
+	}
+	return builder.String()
+}
@@ -6,6 +6,7 @@ const (
 	StatusUnauthorized  = 401
 	StatusForbidden     = 403
 	StatusNotFound      = 404
+	StatusTooMany       = 429
 	StatusInternalError = 500
 )
 
@@ -55,6 +56,10 @@ func NotFound(message string) *Response {
 	return Error(StatusNotFound, message)
 }
 
+func TooManyRequests(message string) *Response {
+	return Error(StatusTooMany, message)
+}
+
 func InternalError(message string) *Response {
 	return Error(StatusInternalError, message)
 }
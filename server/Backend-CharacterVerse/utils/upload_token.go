@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"Backend-CharacterVerse/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// 签名链接过期时间的取整粒度，同一时间段内生成的链接相同，便于浏览器缓存
+const signedURLGranularity = 5 * time.Minute
+
+// ErrFileSecretMissing 未配置签名密钥，无法生成或校验文件访问链接
+var ErrFileSecretMissing = errors.New("未配置 UPLOAD_TOKEN_SECRET，无法签名文件访问链接")
+
+// UploadSystemUserID 后端服务自身上传文件时使用的用户ID，不受上传服务的配额限制
+const UploadSystemUserID = 0
+
+// GenerateUploadToken 签发上传服务令牌，格式与 Upload_Voice_Service 约定：
+// base64url(JSON内容).base64url(HMAC-SHA256签名)
+func GenerateUploadToken(userID uint) (string, time.Time, error) {
+	cfg := config.LoadConfig()
+	expiresAt := time.Now().Add(time.Duration(cfg.UploadTokenTTLMinutes) * time.Minute)
+
+	payload, err := json.Marshal(struct {
+		UserID uint  `json:"uid"`
+		Exp    int64 `json:"exp"`
+	}{userID, expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(cfg.UploadTokenSecret))
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), expiresAt, nil
+}
+
+// SignFileQuery 为文件生成带过期时间的访问参数，格式与 Upload_Voice_Service 约定：
+// signature = base64url(HMAC-SHA256("对象键:过期时间戳"))
+func SignFileQuery(key string) (string, error) {
+	cfg := config.LoadConfig()
+	if cfg.UploadTokenSecret == "" {
+		return "", ErrFileSecretMissing
+	}
+	ttl := time.Duration(cfg.SignedURLTTLMinutes) * time.Minute
+	expires := time.Now().Add(ttl).Truncate(signedURLGranularity).Add(signedURLGranularity).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", fileSignature(cfg.UploadTokenSecret, key, expires))
+	return query.Encode(), nil
+}
+
+// VerifyFileQuery 校验 SignFileQuery 生成的访问参数
+func VerifyFileQuery(key, expiresParam, signature string) error {
+	cfg := config.LoadConfig()
+	if cfg.UploadTokenSecret == "" {
+		return ErrFileSecretMissing
+	}
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New("缺少访问签名")
+	}
+	if time.Now().Unix() > expires {
+		return errors.New("访问链接已过期")
+	}
+	if !hmac.Equal([]byte(signature), []byte(fileSignature(cfg.UploadTokenSecret, key, expires))) {
+		return errors.New("访问签名无效")
+	}
+	return nil
+}
+
+func fileSignature(secret, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
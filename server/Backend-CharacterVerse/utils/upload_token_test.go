@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testUploadSecret = "test-upload-secret"
+
+func TestGenerateUploadToken(t *testing.T) {
+	t.Setenv("UPLOAD_TOKEN_SECRET", testUploadSecret)
+	t.Setenv("UPLOAD_TOKEN_TTL_MINUTES", "10")
+
+	token, expiresAt, err := GenerateUploadToken(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		t.Fatalf("令牌格式错误: %q", token)
+	}
+
+	mac := hmac.New(sha256.New, []byte(testUploadSecret))
+	mac.Write([]byte(parts[0]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[1] != want {
+		t.Fatalf("签名 = %q, 期望 %q", parts[1], want)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		UserID uint  `json:"uid"`
+		Exp    int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.UserID != 42 || payload.Exp != expiresAt.Unix() {
+		t.Fatalf("令牌内容 = %+v, 期望 uid=42 exp=%d", payload, expiresAt.Unix())
+	}
+	if ttl := time.Until(expiresAt); ttl < 9*time.Minute || ttl > 10*time.Minute {
+		t.Fatalf("有效期 = %v, 期望约10分钟", ttl)
+	}
+}
+
+func TestSignFileQueryRoundTrip(t *testing.T) {
+	t.Setenv("UPLOAD_TOKEN_SECRET", testUploadSecret)
+	t.Setenv("SIGNED_URL_TTL_MINUTES", "60")
+
+	encoded, err := SignFileQuery("avatars/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := url.ParseQuery(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, signature := query.Get("expires"), query.Get("signature")
+
+	// 过期时间按粒度取整，同一时间段内签发的链接相同
+	exp, _ := strconv.ParseInt(expires, 10, 64)
+	if exp%int64(signedURLGranularity/time.Second) != 0 {
+		t.Errorf("过期时间未按粒度取整: %d", exp)
+	}
+	if again, _ := SignFileQuery("avatars/a.png"); again != encoded {
+		t.Errorf("同一时间段内签名不一致: %q != %q", again, encoded)
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+		wantErr   bool
+	}{
+		{"有效签名", "avatars/a.png", expires, signature, false},
+		{"对象键不同", "avatars/b.png", expires, signature, true},
+		{"篡改过期时间", "avatars/a.png", strconv.FormatInt(exp+300, 10), signature, true},
+		{"签名错误", "avatars/a.png", expires, signature + "x", true},
+		{"缺少签名", "avatars/a.png", expires, "", true},
+		{"过期时间无效", "avatars/a.png", "abc", signature, true},
+		{"已过期", "avatars/a.png", past, fileSignature(testUploadSecret, "avatars/a.png", time.Now().Add(-time.Minute).Unix()), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyFileQuery(tt.key, tt.expires, tt.signature)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyFileQuery() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileQueryRequiresSecret(t *testing.T) {
+	t.Setenv("UPLOAD_TOKEN_SECRET", "")
+
+	if _, err := SignFileQuery("avatars/a.png"); !errors.Is(err, ErrFileSecretMissing) {
+		t.Fatalf("SignFileQuery() err = %v, 期望 ErrFileSecretMissing", err)
+	}
+	if err := VerifyFileQuery("avatars/a.png", "1", "sig"); !errors.Is(err, ErrFileSecretMissing) {
+		t.Fatalf("VerifyFileQuery() err = %v, 期望 ErrFileSecretMissing", err)
+	}
+}
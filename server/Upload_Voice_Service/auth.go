@@ -0,0 +1,112 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 系统用户ID，后端服务自身上传的文件不受配额限制
+const systemUserID = 0
+
+// uploadClaims 上传令牌内容，由后端使用共享密钥签发
+type uploadClaims struct {
+	UserID uint  `json:"uid"`
+	Exp    int64 `json:"exp"`
+}
+
+// 令牌格式: base64url(JSON内容).base64url(HMAC-SHA256签名)
+func parseUploadToken(secret, token string) (*uploadClaims, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("令牌格式错误")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, errors.New("令牌签名无效")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("令牌格式错误")
+	}
+	var claims uploadClaims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, errors.New("令牌格式错误")
+	}
+	if time.Now().Unix() > claims.Exp {
+		return nil, errors.New("令牌已过期")
+	}
+	return &claims, nil
+}
+
+// 从请求头或查询参数中读取令牌（不读取表单，避免在限制请求体大小前解析上传内容）
+func tokenFromRequest(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if token := c.GetHeader("X-Upload-Token"); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+// 校验上传令牌的中间件
+func tokenAuth(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := tokenFromRequest(c)
+		if token == "" {
+			c.AbortWithStatusJSON(401, gin.H{"error": "缺少上传令牌"})
+			return
+		}
+		claims, err := parseUploadToken(secret, token)
+		if err != nil {
+			c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set("userID", claims.UserID)
+		c.Next()
+	}
+}
+
+// 文件访问签名: base64url(HMAC-SHA256("对象键:过期时间戳"))
+func fileSignature(secret, key string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key + ":" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signFileURL 生成带过期时间的文件访问路径
+func signFileURL(secret, key string, ttl time.Duration) string {
+	expires := time.Now().Add(ttl).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", fileSignature(secret, key, expires))
+	return "/uploads/" + key + "?" + query.Encode()
+}
+
+// 校验文件访问签名
+func verifyFileSignature(secret, key, expiresParam, signature string) error {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || signature == "" {
+		return errors.New("缺少访问签名")
+	}
+	if time.Now().Unix() > expires {
+		return errors.New("访问链接已过期")
+	}
+	if !hmac.Equal([]byte(signature), []byte(fileSignature(secret, key, expires))) {
+		return errors.New("访问签名无效")
+	}
+	return nil
+}
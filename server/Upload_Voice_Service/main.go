@@ -1,23 +1,63 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func main() {
+	// 与后端共享的令牌密钥
+	secret := os.Getenv("UPLOAD_TOKEN_SECRET")
+	if secret == "" {
+		log.Fatal("未配置 UPLOAD_TOKEN_SECRET")
+	}
+	maxFileSize := int64(getEnvInt("UPLOAD_MAX_FILE_MB", 20)) << 20
+	userQuota := int64(getEnvInt("UPLOAD_USER_QUOTA_MB", 200)) << 20
+	uploadDir := getEnv("UPLOAD_DIR", "./uploads")
+	urlTTL := time.Duration(getEnvInt("UPLOAD_URL_TTL_MINUTES", 60)) * time.Minute
+	port := getEnv("PORT", "6060")
+
+	store, err := newFileStore(uploadDir, getEnv("UPLOAD_INDEX_FILE", "./upload_index.json"), userQuota)
+	if err != nil {
+		log.Fatalf("初始化文件存储失败: %v", err)
+	}
+
+	transcoder := &transcoder{
+		ffmpeg: getEnv("FFMPEG_PATH", "ffmpeg"),
+		dir:    getEnv("TRANSCODE_DIR", "./transcoded"),
+	}
+
 	router := gin.Default()
 
 	// CORS支持
 	router.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Upload-Token, Range, If-None-Match")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Range, Content-Length, Accept-Ranges, ETag")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -27,49 +67,111 @@ func main() {
 		c.Next()
 	})
 
-	// 文件大小限制64MB
-	router.MaxMultipartMemory = 64 << 20
+	router.MaxMultipartMemory = maxFileSize
 
-	// 创建上传目录
-	uploadDir := "./uploads"
-	if _, err := os.Stat(uploadDir); os.IsNotExist(err) {
-		os.Mkdir(uploadDir, 0755)
+	api := router.Group("/api")
+	api.Use(tokenAuth(secret))
+	{
+		upload := uploadHandler(store, maxFileSize, secret, urlTTL)
+		api.POST("/upload", upload)
+		api.POST("/upload_voice", upload) // 兼容旧接口
+		api.DELETE("/files/*key", deleteHandler(store, transcoder))
+		api.GET("/quota", func(c *gin.Context) {
+			userID := c.GetUint("userID")
+			c.JSON(http.StatusOK, gin.H{"used": store.Usage(userID), "limit": userQuota})
+		})
 	}
 
-	// 通用文件上传路由
-	router.POST("/api/upload_voice", func(c *gin.Context) {
+	// 文件访问，仅接受带签名且未过期的链接
+	serve := serveHandler(store, transcoder, secret)
+	router.GET("/uploads/*key", serve)
+	router.HEAD("/uploads/*key", serve)
+
+	// 测试页面
+	router.StaticFile("/upload-test", "./upload_test.html")
+
+	fmt.Printf("服务器已启动，访问地址: http://localhost:%s\n", port)
+	router.Run(":" + port)
+}
+
+// 通用文件上传：识别文件类型，按内容哈希保存并计入上传者配额
+func uploadHandler(store *fileStore, maxFileSize int64, secret string, urlTTL time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 限制请求体大小，预留表单字段的空间
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize+1<<20)
+
 		file, err := c.FormFile("file")
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "文件上传失败: " + err.Error()})
 			return
 		}
+		if file.Size > maxFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("文件不能超过%dMB", maxFileSize>>20)})
+			return
+		}
+
+		f, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+			return
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxFileSize+1))
+		if err != nil || int64(len(data)) > maxFileSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取文件失败"})
+			return
+		}
 
-		// 获取文件扩展名并生成唯一文件名
-		ext := filepath.Ext(file.Filename)
-		newFilename := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-		filePath := filepath.Join(uploadDir, newFilename)
+		contentType := sniffContentType(data)
+		if _, ok := allowedTypes[contentType]; !ok {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "不支持的文件类型: " + contentType})
+			return
+		}
 
-		// 保存文件
-		if err := c.SaveUploadedFile(file, filePath); err != nil {
+		record, err := store.Put(c.GetUint("userID"), data, contentType)
+		if errors.Is(err, errQuotaExceeded) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "文件保存失败: " + err.Error()})
 			return
 		}
 
 		// 返回响应
 		c.JSON(http.StatusOK, gin.H{
-			"message":  "文件上传成功",
-			"filename": newFilename,
-			"url":      fmt.Sprintf("/uploads/%s", newFilename),
-			"filetype": ext, // 返回文件类型
+			"message":      "文件上传成功",
+			"filename":     record.Key,
+			"url":          signFileURL(secret, record.Key, urlTTL),
+			"filetype":     allowedTypes[contentType],
+			"content_type": contentType,
+			"size":         record.Size,
 		})
-	})
-
-	// 静态文件访问
-	router.Static("/uploads", uploadDir)
+	}
+}
 
-	// 测试页面
-	router.StaticFile("/upload-test", "./upload_test.html")
+// 删除文件：仅移除当前用户的引用，没有其他上传者时删除文件
+func deleteHandler(store *fileStore, transcoder *transcoder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if !validKey(key) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的文件名"})
+			return
+		}
 
-	fmt.Println("服务器已启动，访问地址: http://localhost:6060")
-	router.Run(":6060")
+		err := store.Delete(c.GetUint("userID"), key)
+		if errors.Is(err, errFileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "文件删除失败: " + err.Error()})
+			return
+		}
+		// 文件已无人引用时一并清理转码缓存
+		if _, err := os.Stat(store.path(key)); errors.Is(err, os.ErrNotExist) {
+			transcoder.Purge(key)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "文件删除成功"})
+	}
 }
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// 允许上传的文件类型及保存时使用的扩展名
+var allowedTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"audio/mpeg": ".mp3",
+	"audio/wav":  ".wav",
+	"audio/ogg":  ".ogg",
+	"audio/webm": ".webm",
+	"audio/flac": ".flac",
+	"audio/aac":  ".aac",
+	"audio/mp4":  ".m4a",
+	"audio/amr":  ".amr",
+}
+
+// sniffContentType 根据文件内容识别类型，不信任客户端提供的扩展名和Content-Type
+func sniffContentType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return "audio/flac"
+	case bytes.HasPrefix(data, []byte("#!AMR")):
+		return "audio/amr"
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return "audio/mpeg"
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		// MP4容器，仅接受音频品牌
+		switch string(data[8:12]) {
+		case "M4A ", "M4B ", "M4P ":
+			return "audio/mp4"
+		}
+		return "video/mp4"
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xF6 == 0xF0:
+		// AAC ADTS帧头
+		return "audio/aac"
+	case len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		// 无ID3标签的MP3帧同步头
+		return "audio/mpeg"
+	}
+
+	switch contentType := http.DetectContentType(data); contentType {
+	case "audio/wave":
+		return "audio/wav"
+	case "application/ogg":
+		return "audio/ogg"
+	case "video/webm":
+		// 浏览器录音通常为webm容器
+		return "audio/webm"
+	default:
+		return contentType
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errQuotaExceeded = errors.New("存储配额不足")
+	errFileNotFound  = errors.New("文件不存在")
+)
+
+// fileRecord 已保存文件的元数据，相同内容只保存一份，记录全部上传者
+type fileRecord struct {
+	Key         string             `json:"key"`
+	Size        int64              `json:"size"`
+	ContentType string             `json:"content_type"`
+	Owners      map[uint]time.Time `json:"owners"` // 上传者 -> 上传时间
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// 索引日志操作
+const (
+	indexOpPut    = "put"
+	indexOpDelete = "del"
+)
+
+// indexEntry 索引日志的一行，记录一次上传或删除
+type indexEntry struct {
+	Op          string    `json:"op"`
+	Key         string    `json:"key"`
+	Owner       uint      `json:"owner"`
+	Size        int64     `json:"size,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// fileStore 按内容哈希分目录保存文件，元数据追加写入索引日志（每行一个JSON），启动时重放
+type fileStore struct {
+	dir       string
+	indexPath string
+	userQuota int64
+	mu        sync.Mutex
+	index     *os.File               // 以追加方式打开的索引日志
+	files     map[string]*fileRecord // 对象键 -> 元数据
+	usage     map[uint]int64         // 用户 -> 已用字节数
+}
+
+func newFileStore(dir, indexPath string, userQuota int64) (*fileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &fileStore{
+		dir:       dir,
+		indexPath: indexPath,
+		userQuota: userQuota,
+		files:     make(map[string]*fileRecord),
+		usage:     make(map[uint]int64),
+	}
+
+	entries, compact, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range s.files {
+		for owner := range record.Owners {
+			s.usage[owner] += record.Size
+		}
+	}
+
+	// 日志中已失效的记录过多或为旧格式时重写为精简日志
+	if compact || entries > 2*s.liveEntries()+1000 {
+		if err := s.compactIndex(); err != nil {
+			return nil, fmt.Errorf("整理索引失败: %w", err)
+		}
+	}
+
+	s.index, err = os.OpenFile(indexPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// 重放索引日志，返回日志条数；旧版整体JSON索引需要转换时 compact 为true
+func (s *fileStore) loadIndex() (entries int, compact bool, err error) {
+	data, err := os.ReadFile(s.indexPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	// 旧版索引为对象键到元数据的整体JSON
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' && !bytes.Contains(trimmed, []byte("\n")) {
+		var legacy map[string]*fileRecord
+		if json.Unmarshal(trimmed, &legacy) == nil {
+			s.files = legacy
+			return len(legacy), true, nil
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry indexEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			// 进程异常退出时最后一行可能不完整
+			log.Printf("跳过损坏的索引记录: %v", err)
+			continue
+		}
+		s.applyEntry(entry)
+		entries++
+	}
+	return entries, false, scanner.Err()
+}
+
+// 将一条日志应用到内存索引
+func (s *fileStore) applyEntry(entry indexEntry) {
+	switch entry.Op {
+	case indexOpPut:
+		record, exists := s.files[entry.Key]
+		if !exists {
+			record = &fileRecord{
+				Key:         entry.Key,
+				Size:        entry.Size,
+				ContentType: entry.ContentType,
+				Owners:      make(map[uint]time.Time),
+				CreatedAt:   entry.At,
+			}
+			s.files[entry.Key] = record
+		}
+		record.Owners[entry.Owner] = entry.At
+	case indexOpDelete:
+		if record, exists := s.files[entry.Key]; exists {
+			delete(record.Owners, entry.Owner)
+			if len(record.Owners) == 0 {
+				delete(s.files, entry.Key)
+			}
+		}
+	}
+}
+
+// 当前有效的日志条数（每个上传者一条）
+func (s *fileStore) liveEntries() int {
+	count := 0
+	for _, record := range s.files {
+		count += len(record.Owners)
+	}
+	return count
+}
+
+// 将当前索引重写为只包含有效记录的日志
+func (s *fileStore) compactIndex() error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range s.files {
+		for owner, at := range record.Owners {
+			if err := encoder.Encode(indexEntry{
+				Op:          indexOpPut,
+				Key:         record.Key,
+				Owner:       owner,
+				Size:        record.Size,
+				ContentType: record.ContentType,
+				At:          at,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return writeFileAtomic(s.indexPath, buf.Bytes())
+}
+
+// 追加一条索引日志，调用方需持有锁
+func (s *fileStore) appendIndex(entry indexEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.index.Write(append(line, '\n'))
+	return err
+}
+
+// 对象键: 哈希前两位/哈希三四位/完整哈希.扩展名
+func contentKey(data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	return hash[0:2] + "/" + hash[2:4] + "/" + hash + ext
+}
+
+func (s *fileStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// Put 保存文件并记录上传者，同一用户重复上传相同内容不重复计入配额
+func (s *fileStore) Put(userID uint, data []byte, contentType string) (*fileRecord, error) {
+	key := contentKey(data, allowedTypes[contentType])
+	size := int64(len(data))
+	path := s.path(key)
+
+	// 内容文件在锁外写入：相同对象键的内容必然相同，并发写入互不影响
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if s.overQuota(userID, size) {
+			return nil, errQuotaExceeded
+		}
+		if err := writeFileAtomic(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.files[key]
+	if exists {
+		if _, owned := record.Owners[userID]; owned {
+			return record, nil
+		}
+	}
+	if userID != systemUserID && s.userQuota > 0 && s.usage[userID]+size > s.userQuota {
+		// 同一用户并发上传时，锁外的预检查可能已放行写入
+		s.removeOrphanLocked(path, exists)
+		return nil, errQuotaExceeded
+	}
+
+	// 写入后到加锁前文件可能已被删除
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if err := writeFileAtomic(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	if err := s.appendIndex(indexEntry{
+		Op:          indexOpPut,
+		Key:         key,
+		Owner:       userID,
+		Size:        size,
+		ContentType: contentType,
+		At:          now,
+	}); err != nil {
+		s.removeOrphanLocked(path, exists)
+		return nil, err
+	}
+
+	if !exists {
+		record = &fileRecord{
+			Key:         key,
+			Size:        size,
+			ContentType: contentType,
+			Owners:      make(map[uint]time.Time),
+			CreatedAt:   now,
+		}
+		s.files[key] = record
+	}
+	record.Owners[userID] = now
+	s.usage[userID] += size
+	return record, nil
+}
+
+// 上传失败时删除没有任何上传者引用的内容文件，否则不会再被清理，调用方需持有锁
+func (s *fileStore) removeOrphanLocked(path string, exists bool) {
+	if exists {
+		return
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("删除无引用的文件失败: %v", err)
+	}
+}
+
+// 预先检查配额，避免为超额的上传写入文件
+func (s *fileStore) overQuota(userID uint, size int64) bool {
+	if userID == systemUserID || s.userQuota <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[userID]+size > s.userQuota
+}
+
+// Delete 移除用户对文件的引用，没有任何上传者引用时删除文件
+func (s *fileStore) Delete(userID uint, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, exists := s.files[key]
+	if !exists {
+		return errFileNotFound
+	}
+	if _, owned := record.Owners[userID]; !owned {
+		return errFileNotFound
+	}
+
+	if err := s.appendIndex(indexEntry{Op: indexOpDelete, Key: key, Owner: userID, At: time.Now()}); err != nil {
+		return err
+	}
+	delete(record.Owners, userID)
+	s.usage[userID] -= record.Size
+	if len(record.Owners) == 0 {
+		delete(s.files, key)
+		if err := os.Remove(s.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup 获取文件元数据，早期未登记的文件返回false
+func (s *fileStore) Lookup(key string) (*fileRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.files[key]
+	return record, ok
+}
+
+// Usage 用户已用空间
+func (s *fileStore) Usage(userID uint) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[userID]
+}
+
+// 先写唯一命名的临时文件再重命名，避免留下写了一半的文件或并发写入互相覆盖
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// 文件访问路径是否安全：兼容早期未分目录保存的文件，禁止访问存储目录以外的文件
+func safeKey(key string) bool {
+	if validKey(key) {
+		return true
+	}
+	return key != "" && !strings.ContainsAny(key, "/\\") && key != "." && key != ".."
+}
+
+// 校验对象键格式（哈希分目录）
+func validKey(key string) bool {
+	parts := strings.Split(key, "/")
+	if len(parts) != 3 || len(parts[0]) != 2 || len(parts[1]) != 2 {
+		return false
+	}
+	name := parts[2]
+	if ext := filepath.Ext(name); ext != "" {
+		name = strings.TrimSuffix(name, ext)
+	}
+	if len(name) != 64 || !strings.HasPrefix(name, parts[0]+parts[1]) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单次转码的超时时间
+const transcodeTimeout = 2 * time.Minute
+
+// transcodeProfile 转码目标格式
+type transcodeProfile struct {
+	ext         string
+	contentType string
+	args        []string // ffmpeg编码参数
+}
+
+// 支持的转码格式，通过 ?format= 指定
+var transcodeProfiles = map[string]transcodeProfile{
+	"mp3":  {".mp3", "audio/mpeg", []string{"-c:a", "libmp3lame", "-b:a", "64k", "-f", "mp3"}},
+	"opus": {".opus", "audio/ogg; codecs=opus", []string{"-c:a", "libopus", "-b:a", "32k", "-f", "ogg"}},
+	"aac":  {".aac", "audio/aac", []string{"-c:a", "aac", "-b:a", "64k", "-f", "adts"}},
+}
+
+var errFFmpegUnavailable = errors.New("服务器未安装ffmpeg，无法转码")
+
+// 转码锁的分片数
+const transcodeLockStripes = 64
+
+// transcoder 调用本地ffmpeg转码音频，结果缓存在磁盘上以支持Range请求
+type transcoder struct {
+	ffmpeg string
+	dir    string
+	locks  [transcodeLockStripes]sync.Mutex // 按目标文件哈希分片，避免同一文件并发转码
+}
+
+// 目标文件对应的转码锁
+func (t *transcoder) lock(target string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(target))
+	return &t.locks[h.Sum32()%transcodeLockStripes]
+}
+
+// Transcode 返回转码后的文件路径，已转码过的直接复用
+func (t *transcoder) Transcode(ctx context.Context, src, cacheName string, profile transcodeProfile) (string, error) {
+	target := filepath.Join(t.dir, cacheName+profile.ext)
+
+	lock := t.lock(target)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+	if _, err := exec.LookPath(t.ffmpeg); err != nil {
+		return "", errFFmpegUnavailable
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcodeTimeout)
+	defer cancel()
+
+	tmp := target + ".tmp"
+	args := append([]string{"-hide_banner", "-loglevel", "error", "-y", "-i", src, "-vn"}, profile.args...)
+	args = append(args, tmp)
+	if output, err := exec.CommandContext(ctx, t.ffmpeg, args...).CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("转码失败: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return target, nil
+}
+
+// Purge 删除文件的全部转码缓存
+func (t *transcoder) Purge(key string) {
+	cacheName := strings.ReplaceAll(key, "/", "_")
+	for _, profile := range transcodeProfiles {
+		os.Remove(filepath.Join(t.dir, cacheName+profile.ext))
+	}
+}
+
+// 通过签名链接访问文件，支持Range、条件请求和按需转码
+func serveHandler(store *fileStore, transcoder *transcoder, secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if !safeKey(key) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errFileNotFound.Error()})
+			return
+		}
+		expires := c.Query("expires")
+		if err := verifyFileSignature(secret, key, expires, c.Query("signature")); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		filePath := store.path(key)
+		contentType := mime.TypeByExtension(path.Ext(key))
+		if record, ok := store.Lookup(key); ok {
+			contentType = record.ContentType
+		}
+
+		// 哈希分目录保存的文件内容不可变，以内容哈希作为ETag
+		name := path.Base(key)
+		etag := strings.TrimSuffix(name, path.Ext(name))
+		if !validKey(key) {
+			etag = ""
+		}
+
+		if format := c.Query("format"); format != "" {
+			profile, ok := transcodeProfiles[format]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的转码格式: " + format})
+				return
+			}
+			if !strings.HasPrefix(contentType, "audio/") {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "仅音频文件支持转码"})
+				return
+			}
+			if _, err := os.Stat(filePath); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": errFileNotFound.Error()})
+				return
+			}
+
+			cacheName := strings.ReplaceAll(key, "/", "_")
+			transcoded, err := transcoder.Transcode(c.Request.Context(), filePath, cacheName, profile)
+			if errors.Is(err, errFFmpegUnavailable) {
+				c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			filePath, contentType = transcoded, profile.contentType
+			if etag != "" {
+				etag += "-" + format
+			}
+		}
+
+		f, err := os.Open(filePath)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errFileNotFound.Error()})
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			c.JSON(http.StatusNotFound, gin.H{"error": errFileNotFound.Error()})
+			return
+		}
+
+		header := c.Writer.Header()
+		if contentType != "" {
+			header.Set("Content-Type", contentType)
+		}
+		if etag != "" {
+			header.Set("ETag", `"`+etag+`"`)
+		}
+		header.Set("Accept-Ranges", "bytes")
+		header.Set("Cache-Control", cacheControl(expires, etag != ""))
+
+		// ServeContent 处理 Range、If-None-Match、If-Modified-Since 等请求头
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime(), f)
+	}
+}
+
+// 缓存时间不超过链接剩余有效期；文件为私有数据，只允许客户端缓存
+func cacheControl(expiresParam string, immutable bool) string {
+	expires, _ := strconv.ParseInt(expiresParam, 10, 64)
+	maxAge := expires - time.Now().Unix()
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	value := "private, max-age=" + strconv.FormatInt(maxAge, 10)
+	if immutable {
+		value += ", immutable"
+	}
+	return value
+}